@@ -0,0 +1,392 @@
+package ngrams
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+const (
+
+	// arpaUnseen is the log10 probability ARPA uses for tokens which can never
+	// occur, such as the sentence start marker.
+	arpaUnseen float64 = -99
+
+	// arpaCountScale converts the probabilities of an imported model into
+	// counts for the store, so an ngram with a probability of 1 is counted
+	// this many times.
+	arpaCountScale float64 = 1000
+)
+
+var (
+	// ErrNotAllOrders indicates that an index can't be exported because it
+	// doesn't record every order of ngram.
+	ErrNotAllOrders = errors.New("index does not record all orders")
+
+	// ErrInvalidARPA indicates that a language model is not in the ARPA
+	// format, or that a token containing whitespace can't be written in it.
+	ErrInvalidARPA = errors.New("invalid ARPA language model")
+)
+
+// WriteARPA writes the index to w as a backoff language model in the ARPA text
+// format, so it can be used by standard language modelling tools. Each ngram
+// of every order is written with the log10 of its probability under the
+// index's smoother, or Katz backoff if there is none, along with the log10
+// backoff weight which normalizes the probabilities of the ngrams it wasn't
+// followed by. The lower orders of Kneser-Ney smoothing are written with their
+// continuation probabilities, as the index uses them. Ngrams containing empty
+// tokens can't be written in the format, so are left out. Every token is
+// checked before anything is written, and ErrInvalidARPA is returned if any
+// contains whitespace. The index must record all orders, and its store must
+// implement stores.Iterator.
+func (i *Index) WriteARPA(w io.Writer) error {
+	if !i.allOrders() {
+		return ErrNotAllOrders
+	}
+
+	grams, err := i.grams()
+	if err != nil {
+		return err
+	}
+
+	smoother := i.Smoother
+	if smoother == nil {
+		smoother = NewKatzBackoff(0)
+	}
+
+	i = i.cached()
+
+	// Each ngram is keyed on its context of n-1 tokens, so the ngrams of each
+	// order are the variations of the keys with one fewer token.
+	orders := make([][]string, i.N)
+	for key, v := range grams {
+		if strings.HasPrefix(key, continuationPrefix) {
+			continue
+		}
+
+		context := stores.SplitKey(key)
+		if len(context) >= i.N {
+			continue
+		}
+
+		ok, err := arpaWritable(context...)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			continue
+		}
+
+		for future := range v {
+			ok, err := arpaWritable(future)
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				continue
+			}
+
+			orders[len(context)] = append(orders[len(context)], stores.JoinKey(append(context, future)))
+		}
+
+		// The sentence start marker is never a future, so contexts ending with
+		// it are listed as unseen ngrams to hold their backoff weights. ARPA
+		// tools also expect it as a unigram.
+		if n := len(context); n > 0 && context[n-1] == SentenceStart {
+			orders[n-1] = append(orders[n-1], key)
+		}
+	}
+
+	if _, ok := grams[SentenceStart]; i.SentenceMarkers && !ok {
+		orders[0] = append(orders[0], SentenceStart)
+	}
+
+	// The unknown token receives the probability of any token which was never
+	// indexed.
+	if _, ok := grams[""][Unknown]; !ok {
+		orders[0] = append(orders[0], Unknown)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "\\data\\\n")
+	for n, ngrams := range orders {
+		fmt.Fprintf(bw, "ngram %d=%d\n", n+1, len(ngrams))
+	}
+
+	for n, ngrams := range orders {
+		fmt.Fprintf(bw, "\n\\%d-grams:\n", n+1)
+		sort.Strings(ngrams)
+		for _, key := range ngrams {
+			tokens := stores.SplitKey(key)
+			context, token := tokens[:n], tokens[n]
+
+			p := arpaUnseen
+			if token != SentenceStart {
+				p = math.Log10(i.arpaProbability(smoother, context, token))
+			}
+
+			fmt.Fprintf(bw, "%s\t%s", formatLogProb(p), strings.Join(tokens, " "))
+
+			// The highest order never backs off, and an ngram which no token
+			// followed has nothing to normalize.
+			if n < i.N-1 {
+				if v, ok := grams[key]; ok && len(v) > 0 {
+					fmt.Fprintf(bw, "\t%s", formatLogProb(i.backoffWeight(smoother, tokens, v)))
+				}
+			}
+
+			fmt.Fprintf(bw, "\n")
+		}
+	}
+
+	fmt.Fprintf(bw, "\n\\end\\\n")
+
+	return bw.Flush()
+}
+
+// backoffWeight returns the log10 backoff weight of a context, which scales
+// the lower order probabilities of the tokens which didn't follow the context
+// so that the probabilities of every token sum to 1.
+func (i *Index) backoffWeight(smoother Smoother, context []string, v stores.Variations) float64 {
+
+	// Stupid backoff is unnormalized, and backs off by a constant.
+	if s, ok := smoother.(*StupidBackoff); ok {
+		return math.Log10(s.Alpha)
+	}
+
+	num, den := 1.0, 1.0
+	for future := range v {
+		if future == "" {
+			continue
+		}
+
+		num -= i.arpaProbability(smoother, context, future)
+		den -= i.arpaProbability(smoother, context[1:], future)
+	}
+
+	if num <= 0 || den <= 0 {
+		return 0
+	}
+
+	return math.Log10(num / den)
+}
+
+// arpaProbability returns the probability of a token following a context in
+// the exported model. Kneser-Ney smoothing only uses raw counts for the
+// highest order, and continuation counts for every lower order, so the lower
+// orders are exported with their continuation probabilities.
+func (i *Index) arpaProbability(smoother Smoother, context []string, token string) float64 {
+	if k, ok := smoother.(*KneserNey); ok && len(context) < i.N-1 {
+		return k.lower(i, context, token)
+	}
+
+	return smoother.Probability(i, context, token)
+}
+
+// formatLogProb formats a log10 probability for an ARPA file.
+func formatLogProb(p float64) string {
+	if p < arpaUnseen || math.IsInf(p, -1) || math.IsNaN(p) {
+		p = arpaUnseen
+	}
+
+	return strconv.FormatFloat(p, 'f', 6, 64)
+}
+
+// arpaWritable returns true if every token can be written in an ARPA file.
+// Empty tokens can't be, so the ngrams containing them are left out, but a
+// token containing whitespace would be split into several, so ErrInvalidARPA
+// is returned instead.
+func arpaWritable(tokens ...string) (bool, error) {
+	ok := true
+	for _, t := range tokens {
+		if strings.IndexFunc(t, isSpace) != -1 {
+			return false, ErrInvalidARPA
+		}
+
+		if t == "" {
+			ok = false
+		}
+	}
+
+	return ok, nil
+}
+
+// isSpace returns true if a rune separates the fields of an ARPA file.
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\v' || r == '\f'
+}
+
+// ReadARPA reads a backoff language model in the ARPA text format into a new
+// index, so that models built with other tools can be used for generation and
+// scoring. N is the highest order in the model, and the index records all
+// orders. The probabilities and backoff weights are kept by an ARPABackoff
+// smoother, which is used unless another is given, and the store is filled
+// with the probability of each ngram scaled to a count, so generation selects
+// tokens in proportion to their probability. The unknown token is only used
+// for scoring, so is never generated. The index uses sentence markers
+// if the model contains the sentence start marker. The options may be nil.
+func ReadARPA(r io.Reader, o *Options) (*Index, error) {
+	m := &ARPABackoff{
+		probs:    make(map[string]float64),
+		backoffs: make(map[string]float64),
+	}
+
+	var counts []int
+	var ngrams [][]string
+	var logprobs []float64
+	var data, done bool
+	var order int
+
+	scanner := bufio.NewScanner(r)
+	for !done && scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case line == "\\data\\":
+			data = true
+			continue
+		case !data:
+			// Anything before the data section is a comment.
+			continue
+		case line == "\\end\\":
+			done = true
+			continue
+		case strings.HasPrefix(line, "ngram "):
+			var n, c int
+			_, err := fmt.Sscanf(line, "ngram %d=%d", &n, &c)
+			if err != nil || n != len(counts)+1 || c < 0 {
+				return nil, ErrInvalidARPA
+			}
+			counts = append(counts, c)
+			continue
+		case strings.HasPrefix(line, "\\") && strings.HasSuffix(line, "-grams:"):
+			n, err := strconv.Atoi(line[1 : len(line)-len("-grams:")])
+			if err != nil || n != order+1 || n > len(counts) {
+				return nil, ErrInvalidARPA
+			}
+			order = n
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if order == 0 || (len(fields) != order+1 && len(fields) != order+2) {
+			return nil, ErrInvalidARPA
+		}
+
+		p, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, ErrInvalidARPA
+		}
+
+		tokens := fields[1 : order+1]
+		key := stores.JoinKey(tokens)
+		m.probs[key] = p
+		if len(fields) == order+2 {
+			m.backoffs[key], err = strconv.ParseFloat(fields[order+1], 64)
+			if err != nil {
+				return nil, ErrInvalidARPA
+			}
+		}
+
+		counts[order-1]--
+		ngrams = append(ngrams, tokens)
+		logprobs = append(logprobs, p)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if !done || len(counts) == 0 {
+		return nil, ErrInvalidARPA
+	}
+
+	// Every ngram declared in the data section must have been read.
+	for _, c := range counts {
+		if c != 0 {
+			return nil, ErrInvalidARPA
+		}
+	}
+
+	opts := new(Options)
+	if o != nil {
+		*opts = *o
+	}
+
+	opts.AllOrders = true
+	_, opts.SentenceMarkers = m.probs[SentenceStart]
+	if opts.Smoother == nil {
+		opts.Smoother = m
+	}
+
+	i := NewIndex(len(counts), opts)
+	for j, tokens := range ngrams {
+		future := tokens[len(tokens)-1]
+		if future == SentenceStart || future == Unknown || logprobs[j] <= arpaUnseen {
+			continue
+		}
+
+		n := int64(math.Round(math.Pow(10, logprobs[j]) * arpaCountScale))
+		if n < 1 {
+			n = 1
+		}
+
+		err = stores.AddN(i.Store, stores.JoinKey(tokens[:len(tokens)-1]), future, n)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return i, nil
+}
+
+// ARPABackoff is a smoother which uses the probabilities and backoff weights of
+// a language model read from an ARPA file. The probability of a token which
+// followed a context in the model is used as-is, otherwise the probability of
+// the token following a shorter context is multiplied by the backoff weight of
+// the context. Tokens not in the model receive the probability of the unknown
+// token, if it's in the model.
+type ARPABackoff struct {
+
+	// probs contains the log10 probability of each ngram, keyed on the ngram.
+	probs map[string]float64
+
+	// backoffs contains the log10 backoff weight of each context, keyed on the
+	// context.
+	backoffs map[string]float64
+}
+
+// Probability returns the probability of a token following a context under the
+// language model.
+func (m *ARPABackoff) Probability(i *Index, context []string, token string) float64 {
+	return math.Pow(10, m.logProb(i.trimContext(context), token))
+}
+
+// logProb returns the log10 probability of a token following a context.
+func (m *ARPABackoff) logProb(context []string, token string) float64 {
+	ngram := append(append(make([]string, 0, len(context)+1), context...), token)
+	if p, ok := m.probs[stores.JoinKey(ngram)]; ok {
+		return p
+	}
+
+	if len(context) == 0 {
+		if p, ok := m.probs[Unknown]; ok {
+			return p
+		}
+
+		return arpaUnseen
+	}
+
+	return m.backoffs[stores.JoinKey(context)] + m.logProb(context[1:], token)
+}
@@ -0,0 +1,193 @@
+package ngrams
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+const arpaModel = `This header is ignored.
+
+\data\
+ngram 1=5
+ngram 2=3
+
+\1-grams:
+-99	<s>	-0.30103
+-0.30103	to	-0.176091
+-0.60206	be
+-0.60206	</s>
+-2	<unk>
+
+\2-grams:
+0	<s> to
+0	to be
+-0.30103	be </s>
+
+\end\
+`
+
+func TestReadARPA(t *testing.T) {
+	i, err := ReadARPA(strings.NewReader(arpaModel), nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, i.N)
+	require.True(t, i.AllOrders)
+	require.True(t, i.SentenceMarkers)
+	require.IsType(t, new(ARPABackoff), i.Smoother)
+
+	// Listed ngrams use their probability, others back off.
+	require.InDelta(t, 1.0, i.Smoother.Probability(i, []string{"to"}, "be"), 1e-6)
+	require.InDelta(t, 0.5, i.Smoother.Probability(i, []string{"be"}, "</s>"), 1e-6)
+	require.InDelta(t, math.Pow(10, -0.176091)*0.25, i.Smoother.Probability(i, []string{"to"}, "</s>"), 1e-6)
+
+	// Unseen tokens receive the probability of the unknown token.
+	require.InDelta(t, 0.01, i.Smoother.Probability(i, nil, "unseen"), 1e-9)
+	require.InDelta(t, math.Pow(10, -2.176091), i.Smoother.Probability(i, []string{"to"}, "unseen"), 1e-9)
+
+	// The store holds the probabilities as counts, without the markers which
+	// are never generated.
+	require.Equal(t, stores.Variations{"to": 500, "be": 250, "</s>": 250}, i.Vocabulary())
+	require.Equal(t, int64(1000), i.Count(SentenceStart, "to"))
+
+	out, err := i.BabbleSentences("", 1)
+	require.NoError(t, err)
+	require.Equal(t, "To be.", out)
+}
+
+func TestReadARPAInvalid(t *testing.T) {
+	for _, m := range []string{
+		"",
+		"\\data\\\nngram 1=1\n\n\\1-grams:\n-1\tto\n",
+		"\\data\\\nngram 1=2\n\n\\1-grams:\n-1\tto\n\n\\end\\\n",
+		"\\data\\\nngram 2=1\n\n\\1-grams:\n-1\tto\n\n\\end\\\n",
+		"\\data\\\nngram 1=1\n\n\\2-grams:\n-1\tto\n\n\\end\\\n",
+		"\\data\\\nngram 1=1\n\n\\1-grams:\nx\tto\n\n\\end\\\n",
+		"\\data\\\nngram 1=1\n\n\\1-grams:\n-1\tto be c\n\n\\end\\\n",
+		"\\data\\\nngram 1=1\n\n\\1-grams:\n-1\tto\tx\n\n\\end\\\n",
+	} {
+		_, err := ReadARPA(strings.NewReader(m), nil)
+		require.Equal(t, ErrInvalidARPA, err, m)
+	}
+}
+
+func TestWriteARPA(t *testing.T) {
+	for _, s := range []Smoother{nil, NewKatzBackoff(0), NewKneserNey(0), NewStupidBackoff(0)} {
+		i := NewIndex(3, &Options{
+			AllOrders:       true,
+			SentenceMarkers: true,
+			Smoother:        s,
+		})
+		i.Parse(smoothingText)
+
+		var b bytes.Buffer
+		err := i.WriteARPA(&b)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(b.String(), "\\data\\\nngram 1=17\nngram 2=19\nngram 3=19\n"), b.String())
+		require.Contains(t, b.String(), "\n-99.000000\t<s>\t")
+
+		// Contexts of sentence start markers are listed to hold their backoff
+		// weights.
+		require.Contains(t, b.String(), "\n-99.000000\t<s> <s>")
+		require.True(t, strings.HasSuffix(b.String(), "\n\\end\\\n"))
+
+		// The model read back gives the same probabilities as the index.
+		l, err := ReadARPA(&b, nil)
+		require.NoError(t, err)
+		require.Equal(t, 3, l.N)
+		require.True(t, l.SentenceMarkers)
+
+		smoother := i.Smoother
+		if smoother == nil {
+			smoother = NewKatzBackoff(0)
+		}
+
+		for _, c := range [][]string{{"to", "be"}, {"not", "to"}, {"the"}, nil} {
+			for _, token := range []string{"be", "question", "or", "unseen"} {
+				want := i.arpaProbability(smoother, c, token)
+				got := l.Smoother.Probability(l, c, token)
+				if _, ok := s.(*StupidBackoff); !ok || token != "unseen" {
+					require.InDelta(t, math.Log10(want), math.Log10(got), 1e-4, "%v %s", c, token)
+				}
+			}
+		}
+
+		// Normalized smoothers still sum to 1.
+		if _, ok := s.(*StupidBackoff); !ok {
+			require.InDelta(t, 1, sumProbabilities(l, []string{"to", "be"}), 1e-3)
+			require.InDelta(t, 1, sumProbabilities(l, []string{"nobler"}), 1e-3)
+		}
+	}
+}
+
+func TestWriteARPAScore(t *testing.T) {
+	data, err := ioutil.ReadFile("training/hemingway.txt")
+	require.NoError(t, err)
+
+	for _, s := range []Smoother{NewKatzBackoff(0), NewKneserNey(0)} {
+		i := NewIndex(3, &Options{
+			SentenceMarkers: true,
+			Smoother:        s,
+		})
+		_, err = i.Parse(string(data[:2000]))
+		require.NoError(t, err)
+
+		var b bytes.Buffer
+		require.NoError(t, i.WriteARPA(&b))
+		l, err := ReadARPA(&b, nil)
+		require.NoError(t, err)
+
+		// Scoring with the exported model gives the same perplexity as the
+		// index, including for text it never learned.
+		for _, text := range []string{
+			"The old man was thin and gaunt with deep wrinkles in the back of his neck.",
+			"To be or not to be, that is the question.",
+		} {
+			want, err := i.Score(text)
+			require.NoError(t, err)
+			got, err := l.Score(text)
+			require.NoError(t, err)
+			require.InDelta(t, want.Perplexity, got.Perplexity, want.Perplexity*1e-5, "%T %s", s, text)
+		}
+	}
+}
+
+func TestWriteARPAInvalid(t *testing.T) {
+	var b bytes.Buffer
+	err := NewIndex(3, nil).WriteARPA(&b)
+	require.Equal(t, ErrNotAllOrders, err)
+
+	i := NewIndex(2, &Options{
+		AllOrders: true,
+		Tokenizer: new(fieldTokenizer),
+	})
+	i.Parse("a|b|c|a b")
+	err = i.WriteARPA(&b)
+	require.Equal(t, ErrInvalidARPA, err)
+
+	// Nothing is written if any token is invalid.
+	require.Equal(t, 0, b.Len())
+}
+
+func TestWriteARPAEmptyTokens(t *testing.T) {
+	i := NewIndex(2, &Options{
+		AllOrders: true,
+		Tokenizer: new(fieldTokenizer),
+	})
+	i.Parse("a|b||c|a|b")
+
+	// Ngrams of the empty token are left out, and their probability is
+	// redistributed by the backoff weights.
+	var b bytes.Buffer
+	require.NoError(t, i.WriteARPA(&b))
+	require.True(t, strings.HasPrefix(b.String(), "\\data\\\nngram 1=4\nngram 2=2\n"), b.String())
+
+	l, err := ReadARPA(&b, nil)
+	require.NoError(t, err)
+	require.InDelta(t, NewKatzBackoff(0).Probability(i, []string{"a"}, "b"), l.Smoother.Probability(l, []string{"a"}, "b"), 1e-6)
+}
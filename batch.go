@@ -0,0 +1,112 @@
+package ngrams
+
+import (
+	"context"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+const (
+
+	// defaultBatchSize is the default number of ngrams buffered by Read and
+	// Parse before they are added to the store.
+	defaultBatchSize int = 1024
+)
+
+// addEntries adds entries to the store in batches of BatchSize.
+func (i *Index) addEntries(ctx context.Context, entries []stores.Entry) error {
+	size := i.batchSize()
+	for len(entries) > 0 {
+		n := size
+		if n > len(entries) {
+			n = len(entries)
+		}
+
+		err := stores.AddBatch(ctx, i.Store, entries[:n])
+		if err != nil {
+			return err
+		}
+		entries = entries[n:]
+	}
+
+	return nil
+}
+
+// batch buffers the ngrams extracted while reading or parsing, so they can be
+// added to the store together rather than one at a time. The ngrams are kept
+// in the order they were extracted, so stores which add them one at a time
+// see the same sequence as without batching.
+type batch struct {
+
+	// size is the number of entries which are buffered before the batch is
+	// flushed.
+	size int
+
+	// entries contains the buffered ngrams.
+	entries []stores.Entry
+}
+
+// batchSize returns the number of ngrams which are added to the store at once.
+func (i *Index) batchSize() int {
+	if i.BatchSize <= 0 {
+		return defaultBatchSize
+	}
+
+	return i.BatchSize
+}
+
+// newBatch returns a batch for the index, sized by its BatchSize.
+func (i *Index) newBatch() *batch {
+	size := i.batchSize()
+	return &batch{
+		size:    size,
+		entries: make([]stores.Entry, 0, size),
+	}
+}
+
+// add buffers a single count of an ngram, flushing the batch to the store once
+// it's full. An ngram which repeats the last one buffered is counted against
+// it. It is a storeFunc.
+func (b *batch) add(ctx context.Context, s stores.Store, key, future string) error {
+	if n := len(b.entries); n > 0 && b.entries[n-1].Key == key && b.entries[n-1].Future == future {
+		b.entries[n-1].Count++
+		return nil
+	}
+
+	b.entries = append(b.entries, stores.Entry{
+		Key:    key,
+		Future: future,
+		Count:  1,
+	})
+
+	if len(b.entries) >= b.size {
+		return b.flush(ctx, s)
+	}
+
+	return nil
+}
+
+// finish flushes the batch once reading or parsing has stopped with err. If it
+// stopped early, such as when the context was cancelled, the ngrams which were
+// already read are still added to every kind of store, so the flush uses a
+// context which is never cancelled, and err is returned.
+func (b *batch) finish(ctx context.Context, s stores.Store, err error) error {
+	if err != nil {
+		b.flush(context.Background(), s)
+		return err
+	}
+
+	return b.flush(ctx, s)
+}
+
+// flush adds every buffered ngram to the store and empties the batch.
+func (b *batch) flush(ctx context.Context, s stores.Store) error {
+	if len(b.entries) == 0 {
+		return nil
+	}
+
+	err := stores.AddBatch(ctx, s, b.entries)
+	b.entries = b.entries[:0]
+
+	return err
+}
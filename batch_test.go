@@ -0,0 +1,121 @@
+package ngrams
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+// batchingStore records the size of each batch added to a memory store.
+type batchingStore struct {
+	*stores.MemoryStore
+	batches []int
+}
+
+func (s *batchingStore) AddBatch(entries []stores.Entry) error {
+	s.batches = append(s.batches, len(entries))
+	return s.MemoryStore.AddBatch(entries)
+}
+
+func TestReadBatches(t *testing.T) {
+	s := &batchingStore{MemoryStore: stores.NewMemoryStore().(*stores.MemoryStore)}
+	i := NewIndex(3, &Options{
+		Store:     s,
+		BatchSize: 4,
+	})
+
+	err := i.Read(strings.NewReader("to be or not to be that is the question"))
+	require.NoError(t, err)
+	require.Equal(t, []int{4, 4}, s.batches)
+
+	// The counts are the same as adding each ngram one at a time.
+	single := NewIndex(3, &Options{
+		Store: &plainStore{stores.NewMemoryStore()},
+	})
+	_, err = single.Parse("to be or not to be that is the question")
+	require.NoError(t, err)
+	require.Equal(t, storeGrams(t, single.Store.(*plainStore).Store), storeGrams(t, s.MemoryStore))
+
+	s.batches = nil
+	_, err = i.Parse("to be or not")
+	require.NoError(t, err)
+	require.Equal(t, []int{2}, s.batches)
+}
+
+func TestBatchRepeats(t *testing.T) {
+	i := NewIndex(2, nil)
+	b := i.newBatch()
+	require.Equal(t, defaultBatchSize, b.size)
+
+	ctx := context.Background()
+	for _, k := range []string{"a", "a", "b", "a"} {
+		require.NoError(t, b.add(ctx, i.Store, k, "x"))
+	}
+
+	// Only consecutive repeats are merged, so the order is kept.
+	require.Equal(t, []stores.Entry{
+		{Key: "a", Future: "x", Count: 2},
+		{Key: "b", Future: "x", Count: 1},
+		{Key: "a", Future: "x", Count: 1},
+	}, b.entries)
+
+	require.NoError(t, b.flush(ctx, i.Store))
+	require.Empty(t, b.entries)
+	require.Equal(t, int64(3), i.Count("a", "x"))
+}
+
+func TestParseFlushError(t *testing.T) {
+	i := NewIndex(3, &Options{
+		Store: &MockStore{errAdd: true},
+	})
+
+	_, err := i.Parse("to be or not")
+	require.Error(t, err)
+}
+
+// cancellingReader returns its first chunk, then cancels a context and returns
+// its second.
+type cancellingReader struct {
+	chunks []string
+	cancel func()
+}
+
+func (r *cancellingReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+
+	if len(r.chunks) == 1 {
+		r.cancel()
+	}
+
+	n := copy(p, r.chunks[0])
+	r.chunks = r.chunks[1:]
+
+	return n, nil
+}
+
+func TestReadContextKeepsBuffered(t *testing.T) {
+	for _, s := range []stores.Store{
+		stores.NewMemoryStore(),
+		&plainStore{stores.NewMemoryStore()},
+	} {
+		ctx, cancel := context.WithCancel(context.Background())
+		i := NewIndex(3, &Options{Store: s})
+		err := i.ReadContext(ctx, &cancellingReader{
+			chunks: []string{"to be or not ", "to be that is the question"},
+			cancel: cancel,
+		})
+		require.Equal(t, context.Canceled, err)
+
+		// The ngrams read before the context was cancelled are kept, whether
+		// or not the store implements stores.Batcher.
+		require.Equal(t, int64(1), i.Count("to", "be", "or"))
+		require.Equal(t, int64(1), i.Count("be", "or", "not"))
+	}
+}
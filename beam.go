@@ -0,0 +1,161 @@
+package ngrams
+
+import (
+	"math"
+	"sort"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+// Sequence is a continuation of a prompt found by beam search.
+type Sequence struct {
+
+	// Tokens contains the tokens which were generated following the prompt.
+	Tokens []string
+
+	// Text is the prompt and generated tokens, formatted by the tokenizer.
+	Text string
+
+	// LogProb is the total log-probability of the generated tokens (including
+	// the end of sentence marker, if the sequence ended a sentence).
+	LogProb float64
+}
+
+// beam is a partial sequence being expanded during beam search.
+type beam struct {
+
+	// tokens contains the tokens generated so far.
+	tokens []string
+
+	// context contains the last N-1 tokens of the prompt and generated tokens.
+	context []string
+
+	// logProb is the cumulative log-probability of the generated tokens.
+	logProb float64
+
+	// done indicates the beam can't be expanded any further, either because
+	// it reached the end of a sentence or there were no variations to follow.
+	done bool
+}
+
+// Beam returns the most probable continuations of a prompt, each up to length
+// tokens long, using a beam search which keeps the width most probable partial
+// sequences at each step. The sequences are sorted from most to least probable.
+// Unlike Babble, the results are deterministic for a given index. If the index
+// uses sentence markers, sequences stop at the end of a sentence.
+func (i *Index) Beam(prompt string, length, width int) ([]Sequence, error) {
+	if width < 1 {
+		width = 1
+	}
+
+	i = i.cached()
+	start := i.Tokenizer.Tokenize(prompt)
+	context := i.trimContext(start)
+	if len(start) == 0 && i.SentenceMarkers {
+		context = i.sentenceStart()
+	}
+
+	// If there's nothing to follow the prompt there's nothing to search.
+	if len(i.beamVariations(context)) == 0 {
+		return nil, ErrNoResult
+	}
+
+	beams := []*beam{{
+		context: context,
+	}}
+
+	for step := 0; step < length; step++ {
+		next := make([]*beam, 0, len(beams)*width)
+		expanded := false
+		for _, b := range beams {
+			if b.done {
+				next = append(next, b)
+				continue
+			}
+
+			v := i.beamVariations(b.context)
+			if len(v) == 0 {
+				b.done = true
+				next = append(next, b)
+				continue
+			}
+
+			expanded = true
+			for _, c := range candidates(v) {
+				next = append(next, i.expandBeam(b, c.token, v))
+			}
+		}
+
+		if !expanded {
+			break
+		}
+
+		sortBeams(next)
+		if len(next) > width {
+			next = next[:width]
+		}
+		beams = next
+	}
+
+	seqs := make([]Sequence, len(beams))
+	for j, b := range beams {
+		seqs[j] = Sequence{
+			Tokens:  b.tokens,
+			Text:    i.Tokenizer.Format(append(append([]string{}, start...), b.tokens...)),
+			LogProb: b.logProb,
+		}
+	}
+
+	return seqs, nil
+}
+
+// beamVariations returns the variations which can follow a beam's context.
+// If the index records all orders, unseen contexts back off to shorter ones.
+func (i *Index) beamVariations(context []string) stores.Variations {
+	if i.allOrders() {
+		_, v := i.backoff(context)
+		return v
+	}
+
+	_, v := i.Lookup(context)
+	return v
+}
+
+// expandBeam returns a new beam which extends a beam with a token selected
+// from a set of variations.
+func (i *Index) expandBeam(b *beam, token string, v stores.Variations) *beam {
+	var p float64
+	if i.Smoother != nil {
+		p = i.Smoother.Probability(i, b.context, token)
+	} else {
+		p = float64(v[token]) / float64(v.Total())
+	}
+
+	nb := &beam{
+		tokens:  b.tokens,
+		context: b.context,
+		logProb: b.logProb + math.Log(p),
+	}
+
+	if token == SentenceEnd {
+		nb.done = true
+		return nb
+	}
+
+	nb.tokens = append(append(make([]string, 0, len(b.tokens)+1), b.tokens...), token)
+	nb.context = i.trimContext(append(append(make([]string, 0, len(b.context)+1), b.context...), token))
+
+	return nb
+}
+
+// sortBeams sorts beams from most to least probable. Beams with the same
+// probability are sorted by their tokens so the order is always the same.
+func sortBeams(beams []*beam) {
+	sort.SliceStable(beams, func(a, b int) bool {
+		if beams[a].logProb != beams[b].logProb {
+			return beams[a].logProb > beams[b].logProb
+		}
+
+		return stores.JoinKey(beams[a].tokens) < stores.JoinKey(beams[b].tokens)
+	})
+}
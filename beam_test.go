@@ -0,0 +1,96 @@
+package ngrams
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBeam(t *testing.T) {
+	i := NewIndex(3, nil)
+	i.Parse("to be or not to be or not to be or to be, that is the question.")
+
+	seqs, err := i.Beam("to be", 3, 2)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(seqs))
+
+	// "to be" -> or (3/4), "be or" -> not (2/3), "or not" -> to (1).
+	require.Equal(t, []string{"or", "not", "to"}, seqs[0].Tokens)
+	require.Equal(t, "To be or not to.", seqs[0].Text)
+	require.InDelta(t, math.Log(0.75*2.0/3.0), seqs[0].LogProb, 1e-9)
+
+	// Both "to be, that is" and "to be or to be" have the same probability,
+	// so are sorted by their tokens.
+	require.Equal(t, []string{",", "that", "is"}, seqs[1].Tokens)
+	require.InDelta(t, math.Log(0.25), seqs[1].LogProb, 1e-9)
+
+	// The results are always the same.
+	again, err := i.Beam("to be", 3, 2)
+	require.NoError(t, err)
+	require.Equal(t, seqs, again)
+
+	// A wider beam returns more sequences, sorted by probability.
+	seqs, err = i.Beam("to be", 2, 10)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(seqs))
+	for j := 1; j < len(seqs); j++ {
+		require.True(t, seqs[j-1].LogProb >= seqs[j].LogProb)
+	}
+
+	// A width of 0 is the same as greedy search.
+	seqs, err = i.Beam("to be", 1, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(seqs))
+	require.Equal(t, []string{"or"}, seqs[0].Tokens)
+
+	// Sequences stop early if there's nothing to follow them.
+	seqs, err = i.Beam("is the", 5, 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"question", "."}, seqs[0].Tokens)
+
+	_, err = i.Beam("unseen prompt", 5, 1)
+	require.Equal(t, ErrNoResult, err)
+}
+
+func TestBeamSentences(t *testing.T) {
+	i := NewIndex(3, &Options{
+		SentenceMarkers: true,
+		Smoother:        NewKneserNey(0),
+	})
+	i.Parse(sentencesText)
+
+	seqs, err := i.Beam("", 20, 3)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(seqs))
+	require.Equal(t, []string{"the", "cat", "!"}, seqs[0].Tokens)
+	require.Equal(t, "The cat!", seqs[0].Text)
+	for _, s := range seqs {
+		require.NotContains(t, s.Tokens, SentenceEnd)
+		require.False(t, math.IsInf(s.LogProb, 0))
+	}
+
+	// Unseen prompts back off when all orders are recorded.
+	i = NewIndex(3, &Options{
+		AllOrders: true,
+	})
+	i.Parse(sentencesText)
+	seqs, err = i.Beam("unseen prompt", 3, 1)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(seqs[0].Tokens))
+}
+
+func TestSortBeams(t *testing.T) {
+	beams := []*beam{
+		{tokens: []string{"a b", "c"}},
+		{tokens: []string{"a", "b c"}},
+		{tokens: []string{"z"}, logProb: -1},
+	}
+
+	// Beams with the same probability are ordered by their keys, so tokens
+	// containing spaces can't make two beams compare as equal.
+	sortBeams(beams)
+	require.Equal(t, []string{"a", "b c"}, beams[0].tokens)
+	require.Equal(t, []string{"a b", "c"}, beams[1].tokens)
+	require.Equal(t, []string{"z"}, beams[2].tokens)
+}
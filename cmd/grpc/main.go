@@ -14,9 +14,12 @@ import (
 	"syscall"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/mochi-co/ngrams"
 	v1 "github.com/mochi-co/ngrams/cmd/grpc/v1"
+	"github.com/mochi-co/ngrams/stores"
 
 	"github.com/jamiealquiza/envy"
 )
@@ -37,11 +40,22 @@ func main() {
 
 	// Optionally override the port the gRPC server serves on.
 	port := flag.Int("port", 50051, "port to serve grpc on")
+	store := flag.String("store", "", "file to persist ngrams to; kept in memory if blank")
 	envy.Parse("NGRAMS") // Expose environment variables as NGRAMS_PORT, etc.
 
-	// Configure the Ngrams indexer to index trigrams and use the default options.
+	// Configure the Ngrams indexer to index trigrams, persisting them to a file
+	// store if one was given so they survive restarts.
+	o := new(ngrams.Options)
+	if *store != "" {
+		fs, err := stores.NewFileStore(*store)
+		if err != nil {
+			log.Fatalf("failed to open store: %v", err)
+		}
+		o.Store = fs
+	}
+
 	server := &ngramService{
-		index: ngrams.NewIndex(3, nil),
+		index: ngrams.NewIndex(3, o),
 	}
 
 	// Setup the gRPC server with our ngram service.
@@ -80,9 +94,9 @@ type ngramService struct {
 // Learn trains the indexer on a body of data.
 func (s *ngramService) Learn(ctx context.Context, req *v1.LearnRequest) (resp *v1.LearnResponse, err error) {
 
-	tokens, err := s.index.Parse(req.Body)
+	tokens, err := s.index.ParseContext(ctx, req.Body)
 	if err != nil {
-		return
+		return nil, contextError(err)
 	}
 
 	resp = &v1.LearnResponse{
@@ -101,9 +115,14 @@ func (s *ngramService) Generate(ctx context.Context, req *v1.GenerateRequest) (r
 		tokenLen = req.Limit
 	}
 
-	out, err := s.index.Babble("", int(tokenLen))
+	out, err := s.index.GenerateContext(ctx, "", int(tokenLen), &ngrams.GenerateOptions{
+		Temperature: req.Temperature,
+		TopK:        int(req.TopK),
+		TopP:        req.TopP,
+		Greedy:      req.Greedy,
+	})
 	if err != nil {
-		return
+		return nil, contextError(err)
 	}
 
 	resp = &v1.GenerateResponse{
@@ -113,3 +132,61 @@ func (s *ngramService) Generate(ctx context.Context, req *v1.GenerateRequest) (r
 
 	return
 }
+
+// Stats returns statistics about the learned ngrams.
+func (s *ngramService) Stats(ctx context.Context, req *v1.StatsRequest) (resp *v1.StatsResponse, err error) {
+
+	var top int64 = 10
+
+	if req.Top > 0 {
+		top = req.Top
+	}
+
+	st, err := s.index.StatsTop(int(top))
+	if err != nil {
+		return nil, err
+	}
+
+	resp = &v1.StatsResponse{
+		Tokens:          st.Tokens,
+		Vocabulary:      int64(st.Vocabulary),
+		Keys:            int64(st.Keys),
+		Variations:      int64(st.Variations),
+		CountOfCounts:   make(map[int64]int64, len(st.CountOfCounts)),
+		BranchingFactor: st.BranchingFactor,
+		Entropy: &v1.EntropyStats{
+			Min:         st.Entropy.Min,
+			Max:         st.Entropy.Max,
+			Mean:        st.Entropy.Mean,
+			Conditional: st.Entropy.Conditional,
+		},
+	}
+
+	for c, n := range st.CountOfCounts {
+		resp.CountOfCounts[c] = int64(n)
+	}
+
+	for _, ng := range st.Top {
+		resp.Top = append(resp.Top, &v1.NgramCount{
+			Ngram: ng.Ngram,
+			Count: ng.Count,
+		})
+	}
+
+	return
+}
+
+// contextError converts an error caused by a cancelled or expired request
+// context, or by invalid request options, into the matching gRPC status error.
+func contextError(err error) error {
+	switch err {
+	case context.Canceled:
+		return status.Error(codes.Canceled, err.Error())
+	case context.DeadlineExceeded:
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case ngrams.ErrInvalidSampling:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return err
+}
@@ -106,7 +106,17 @@ func (m *LearnResponse) GetParsedTokens() int64 {
 
 type GenerateRequest struct {
 	// limit is the target length of the output in tokens.
-	Limit                int64    `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	Limit int64 `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	// temperature scales the probability of each token before selection.
+	// Values below 1 are more conservative, values above 1 more creative.
+	Temperature float64 `protobuf:"fixed64,2,opt,name=temperature,proto3" json:"temperature,omitempty"`
+	// top_k limits selection to the k most likely tokens (0 is unlimited).
+	TopK int64 `protobuf:"varint,3,opt,name=top_k,json=topK,proto3" json:"top_k,omitempty"`
+	// top_p limits selection to the most likely tokens whose probabilities
+	// sum to at least p (0 is unlimited).
+	TopP float64 `protobuf:"fixed64,4,opt,name=top_p,json=topP,proto3" json:"top_p,omitempty"`
+	// greedy always selects the most likely token.
+	Greedy               bool     `protobuf:"varint,5,opt,name=greedy,proto3" json:"greedy,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -144,6 +154,34 @@ func (m *GenerateRequest) GetLimit() int64 {
 	return 0
 }
 
+func (m *GenerateRequest) GetTemperature() float64 {
+	if m != nil {
+		return m.Temperature
+	}
+	return 0
+}
+
+func (m *GenerateRequest) GetTopK() int64 {
+	if m != nil {
+		return m.TopK
+	}
+	return 0
+}
+
+func (m *GenerateRequest) GetTopP() float64 {
+	if m != nil {
+		return m.TopP
+	}
+	return 0
+}
+
+func (m *GenerateRequest) GetGreedy() bool {
+	if m != nil {
+		return m.Greedy
+	}
+	return false
+}
+
 type GenerateResponse struct {
 	// body is the output that was generated.
 	Body string `protobuf:"bytes,1,opt,name=body,proto3" json:"body,omitempty"`
@@ -193,31 +231,317 @@ func (m *GenerateResponse) GetLimit() int64 {
 	return 0
 }
 
+type StatsRequest struct {
+	// top is the number of most frequent ngrams to return (default 10).
+	Top                  int64    `protobuf:"varint,1,opt,name=top,proto3" json:"top,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StatsRequest) Reset()         { *m = StatsRequest{} }
+func (m *StatsRequest) String() string { return proto.CompactTextString(m) }
+func (*StatsRequest) ProtoMessage()    {}
+func (*StatsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_566f5b74984976ae, []int{4}
+}
+
+func (m *StatsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StatsRequest.Unmarshal(m, b)
+}
+func (m *StatsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StatsRequest.Marshal(b, m, deterministic)
+}
+func (m *StatsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StatsRequest.Merge(m, src)
+}
+func (m *StatsRequest) XXX_Size() int {
+	return xxx_messageInfo_StatsRequest.Size(m)
+}
+func (m *StatsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StatsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StatsRequest proto.InternalMessageInfo
+
+func (m *StatsRequest) GetTop() int64 {
+	if m != nil {
+		return m.Top
+	}
+	return 0
+}
+
+type NgramCount struct {
+	// ngram contains the tokens of the ngram.
+	Ngram []string `protobuf:"bytes,1,rep,name=ngram,proto3" json:"ngram,omitempty"`
+	// count is the number of times the ngram was indexed.
+	Count                int64    `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NgramCount) Reset()         { *m = NgramCount{} }
+func (m *NgramCount) String() string { return proto.CompactTextString(m) }
+func (*NgramCount) ProtoMessage()    {}
+func (*NgramCount) Descriptor() ([]byte, []int) {
+	return fileDescriptor_566f5b74984976ae, []int{5}
+}
+
+func (m *NgramCount) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NgramCount.Unmarshal(m, b)
+}
+func (m *NgramCount) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NgramCount.Marshal(b, m, deterministic)
+}
+func (m *NgramCount) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NgramCount.Merge(m, src)
+}
+func (m *NgramCount) XXX_Size() int {
+	return xxx_messageInfo_NgramCount.Size(m)
+}
+func (m *NgramCount) XXX_DiscardUnknown() {
+	xxx_messageInfo_NgramCount.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NgramCount proto.InternalMessageInfo
+
+func (m *NgramCount) GetNgram() []string {
+	if m != nil {
+		return m.Ngram
+	}
+	return nil
+}
+
+func (m *NgramCount) GetCount() int64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+type EntropyStats struct {
+	// min is the lowest entropy of any key, in bits.
+	Min float64 `protobuf:"fixed64,1,opt,name=min,proto3" json:"min,omitempty"`
+	// max is the highest entropy of any key, in bits.
+	Max float64 `protobuf:"fixed64,2,opt,name=max,proto3" json:"max,omitempty"`
+	// mean is the mean entropy of the keys, in bits.
+	Mean float64 `protobuf:"fixed64,3,opt,name=mean,proto3" json:"mean,omitempty"`
+	// conditional is the entropy of the next token given the context, in bits.
+	Conditional          float64  `protobuf:"fixed64,4,opt,name=conditional,proto3" json:"conditional,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EntropyStats) Reset()         { *m = EntropyStats{} }
+func (m *EntropyStats) String() string { return proto.CompactTextString(m) }
+func (*EntropyStats) ProtoMessage()    {}
+func (*EntropyStats) Descriptor() ([]byte, []int) {
+	return fileDescriptor_566f5b74984976ae, []int{6}
+}
+
+func (m *EntropyStats) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EntropyStats.Unmarshal(m, b)
+}
+func (m *EntropyStats) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EntropyStats.Marshal(b, m, deterministic)
+}
+func (m *EntropyStats) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EntropyStats.Merge(m, src)
+}
+func (m *EntropyStats) XXX_Size() int {
+	return xxx_messageInfo_EntropyStats.Size(m)
+}
+func (m *EntropyStats) XXX_DiscardUnknown() {
+	xxx_messageInfo_EntropyStats.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EntropyStats proto.InternalMessageInfo
+
+func (m *EntropyStats) GetMin() float64 {
+	if m != nil {
+		return m.Min
+	}
+	return 0
+}
+
+func (m *EntropyStats) GetMax() float64 {
+	if m != nil {
+		return m.Max
+	}
+	return 0
+}
+
+func (m *EntropyStats) GetMean() float64 {
+	if m != nil {
+		return m.Mean
+	}
+	return 0
+}
+
+func (m *EntropyStats) GetConditional() float64 {
+	if m != nil {
+		return m.Conditional
+	}
+	return 0
+}
+
+type StatsResponse struct {
+	// tokens is the number of tokens which were indexed.
+	Tokens int64 `protobuf:"varint,1,opt,name=tokens,proto3" json:"tokens,omitempty"`
+	// vocabulary is the number of distinct tokens which were indexed.
+	Vocabulary int64 `protobuf:"varint,2,opt,name=vocabulary,proto3" json:"vocabulary,omitempty"`
+	// keys is the number of keys in the store.
+	Keys int64 `protobuf:"varint,3,opt,name=keys,proto3" json:"keys,omitempty"`
+	// variations is the number of variations in the store.
+	Variations int64 `protobuf:"varint,4,opt,name=variations,proto3" json:"variations,omitempty"`
+	// count_of_counts contains the number of ngrams indexed exactly c times,
+	// keyed on c.
+	CountOfCounts map[int64]int64 `protobuf:"bytes,5,rep,name=count_of_counts,json=countOfCounts,proto3" json:"count_of_counts,omitempty" protobuf_key:"varint,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	// branching_factor is the mean number of variations of each key.
+	BranchingFactor float64 `protobuf:"fixed64,6,opt,name=branching_factor,json=branchingFactor,proto3" json:"branching_factor,omitempty"`
+	// entropy summarises the entropy of the variations of each key.
+	Entropy *EntropyStats `protobuf:"bytes,7,opt,name=entropy,proto3" json:"entropy,omitempty"`
+	// top contains the most frequent ngrams, most frequent first.
+	Top                  []*NgramCount `protobuf:"bytes,8,rep,name=top,proto3" json:"top,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *StatsResponse) Reset()         { *m = StatsResponse{} }
+func (m *StatsResponse) String() string { return proto.CompactTextString(m) }
+func (*StatsResponse) ProtoMessage()    {}
+func (*StatsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_566f5b74984976ae, []int{7}
+}
+
+func (m *StatsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StatsResponse.Unmarshal(m, b)
+}
+func (m *StatsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StatsResponse.Marshal(b, m, deterministic)
+}
+func (m *StatsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StatsResponse.Merge(m, src)
+}
+func (m *StatsResponse) XXX_Size() int {
+	return xxx_messageInfo_StatsResponse.Size(m)
+}
+func (m *StatsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_StatsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StatsResponse proto.InternalMessageInfo
+
+func (m *StatsResponse) GetTokens() int64 {
+	if m != nil {
+		return m.Tokens
+	}
+	return 0
+}
+
+func (m *StatsResponse) GetVocabulary() int64 {
+	if m != nil {
+		return m.Vocabulary
+	}
+	return 0
+}
+
+func (m *StatsResponse) GetKeys() int64 {
+	if m != nil {
+		return m.Keys
+	}
+	return 0
+}
+
+func (m *StatsResponse) GetVariations() int64 {
+	if m != nil {
+		return m.Variations
+	}
+	return 0
+}
+
+func (m *StatsResponse) GetCountOfCounts() map[int64]int64 {
+	if m != nil {
+		return m.CountOfCounts
+	}
+	return nil
+}
+
+func (m *StatsResponse) GetBranchingFactor() float64 {
+	if m != nil {
+		return m.BranchingFactor
+	}
+	return 0
+}
+
+func (m *StatsResponse) GetEntropy() *EntropyStats {
+	if m != nil {
+		return m.Entropy
+	}
+	return nil
+}
+
+func (m *StatsResponse) GetTop() []*NgramCount {
+	if m != nil {
+		return m.Top
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*LearnRequest)(nil), "v1.LearnRequest")
 	proto.RegisterType((*LearnResponse)(nil), "v1.LearnResponse")
 	proto.RegisterType((*GenerateRequest)(nil), "v1.GenerateRequest")
 	proto.RegisterType((*GenerateResponse)(nil), "v1.GenerateResponse")
+	proto.RegisterType((*StatsRequest)(nil), "v1.StatsRequest")
+	proto.RegisterType((*NgramCount)(nil), "v1.NgramCount")
+	proto.RegisterType((*EntropyStats)(nil), "v1.EntropyStats")
+	proto.RegisterType((*StatsResponse)(nil), "v1.StatsResponse")
+	proto.RegisterMapType((map[int64]int64)(nil), "v1.StatsResponse.CountOfCountsEntry")
 }
 
 func init() { proto.RegisterFile("v1/ngrams.proto", fileDescriptor_566f5b74984976ae) }
 
 var fileDescriptor_566f5b74984976ae = []byte{
-	// 216 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x2f, 0x33, 0xd4, 0xcf,
-	0x4b, 0x2f, 0x4a, 0xcc, 0x2d, 0xd6, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x2a, 0x33, 0x54,
-	0x52, 0xe2, 0xe2, 0xf1, 0x49, 0x4d, 0x2c, 0xca, 0x0b, 0x4a, 0x2d, 0x2c, 0x4d, 0x2d, 0x2e, 0x11,
-	0x12, 0xe2, 0x62, 0x49, 0xca, 0x4f, 0xa9, 0x94, 0x60, 0x54, 0x60, 0xd4, 0xe0, 0x0c, 0x02, 0xb3,
-	0x95, 0x4c, 0xb8, 0x78, 0xa1, 0x6a, 0x8a, 0x0b, 0xf2, 0xf3, 0x8a, 0x53, 0x85, 0x94, 0xb9, 0x78,
-	0x0b, 0x12, 0x8b, 0x8a, 0x53, 0x53, 0xe2, 0x4b, 0xf2, 0xb3, 0x53, 0xf3, 0x8a, 0xc1, 0xaa, 0x99,
-	0x83, 0x78, 0x20, 0x82, 0x21, 0x60, 0x31, 0x25, 0x75, 0x2e, 0x7e, 0xf7, 0xd4, 0xbc, 0xd4, 0xa2,
-	0xc4, 0x92, 0x54, 0x98, 0xe1, 0x22, 0x5c, 0xac, 0x39, 0x99, 0xb9, 0x99, 0x25, 0x50, 0xf5, 0x10,
-	0x8e, 0x92, 0x0d, 0x97, 0x00, 0x42, 0x21, 0xd4, 0x06, 0x2c, 0xce, 0x40, 0xe8, 0x66, 0x42, 0xd2,
-	0x6d, 0x54, 0xcc, 0xc5, 0xe3, 0x07, 0xf2, 0x54, 0x70, 0x6a, 0x51, 0x59, 0x66, 0x72, 0xaa, 0x90,
-	0x0e, 0x17, 0x2b, 0xd8, 0xb1, 0x42, 0x02, 0x7a, 0x65, 0x86, 0x7a, 0xc8, 0x7e, 0x93, 0x12, 0x44,
-	0x12, 0x81, 0xda, 0x63, 0xca, 0xc5, 0x01, 0xb3, 0x5b, 0x48, 0x18, 0x24, 0x8d, 0xe6, 0x64, 0x29,
-	0x11, 0x54, 0x41, 0x88, 0xb6, 0x24, 0x36, 0x70, 0x00, 0x1a, 0x03, 0x02, 0x00, 0x00, 0xff, 0xff,
-	0x3d, 0x4d, 0x0d, 0x9b, 0x53, 0x01, 0x00, 0x00,
+	// 553 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x54, 0x51, 0x8f, 0xd2, 0x40,
+	0x10, 0xce, 0x52, 0xca, 0xdd, 0x0d, 0x20, 0xb8, 0x77, 0xb9, 0x34, 0x3c, 0x98, 0xa6, 0xfa, 0x50,
+	0xcd, 0x05, 0x03, 0x6a, 0x72, 0x31, 0x3e, 0x98, 0x18, 0xf5, 0xc1, 0x8b, 0x9a, 0x9e, 0xef, 0x64,
+	0x29, 0x03, 0xd7, 0x00, 0xbb, 0x75, 0xbb, 0x34, 0xf6, 0xdd, 0x37, 0x7f, 0x85, 0xff, 0xd4, 0xec,
+	0xb0, 0x3d, 0x8a, 0xfa, 0xc4, 0xcc, 0xd7, 0x9d, 0x99, 0x6f, 0xbe, 0x6f, 0x17, 0x18, 0x94, 0x93,
+	0xe7, 0x72, 0xa5, 0xc5, 0xb6, 0x18, 0xe7, 0x5a, 0x19, 0xc5, 0x5b, 0xe5, 0x24, 0x8a, 0xa0, 0x77,
+	0x83, 0x42, 0xcb, 0x04, 0xbf, 0xef, 0xb0, 0x30, 0x9c, 0x43, 0x7b, 0xae, 0x16, 0x55, 0xc0, 0x42,
+	0x16, 0x9f, 0x25, 0x14, 0x47, 0x2f, 0xa1, 0xef, 0xce, 0x14, 0xb9, 0x92, 0x05, 0xf2, 0xc7, 0xd0,
+	0xcf, 0x85, 0x2e, 0x70, 0x31, 0x33, 0x6a, 0x8d, 0xb2, 0xa0, 0xd3, 0x5e, 0xd2, 0xdb, 0x83, 0xdf,
+	0x08, 0x8b, 0x7e, 0x31, 0x18, 0x7c, 0x44, 0x89, 0x5a, 0x18, 0xac, 0xbb, 0x5f, 0x80, 0xbf, 0xc9,
+	0xb6, 0x99, 0x71, 0x05, 0xfb, 0x84, 0x87, 0xd0, 0x35, 0xb8, 0xcd, 0xed, 0xc9, 0x9d, 0xc6, 0xa0,
+	0x15, 0xb2, 0x98, 0x25, 0x4d, 0x88, 0x9f, 0x83, 0x6f, 0x54, 0x3e, 0x5b, 0x07, 0x1e, 0xd5, 0xb5,
+	0x8d, 0xca, 0x3f, 0xd5, 0x60, 0x1e, 0xb4, 0xa9, 0xc0, 0x82, 0x5f, 0xf9, 0x25, 0x74, 0x56, 0x1a,
+	0x71, 0x51, 0x05, 0x7e, 0xc8, 0xe2, 0xd3, 0xc4, 0x65, 0xd1, 0x1b, 0x18, 0x1e, 0xc8, 0xb8, 0x35,
+	0xfe, 0xb3, 0xeb, 0x81, 0x61, 0xab, 0xc1, 0x30, 0x0a, 0xa1, 0x77, 0x6b, 0x84, 0x29, 0xea, 0x3d,
+	0x86, 0xe0, 0x19, 0x95, 0xbb, 0x2d, 0x6c, 0x18, 0x5d, 0x03, 0x7c, 0xb6, 0xda, 0xbe, 0x53, 0x3b,
+	0x49, 0x7b, 0x92, 0xd2, 0x01, 0x0b, 0xbd, 0xf8, 0x2c, 0xd9, 0x27, 0x16, 0x4d, 0xed, 0xe7, 0xba,
+	0x37, 0x25, 0xd1, 0x1d, 0xf4, 0xde, 0x4b, 0xa3, 0x55, 0x5e, 0xd1, 0x08, 0xdb, 0x7b, 0x9b, 0x49,
+	0xea, 0xcd, 0x12, 0x1b, 0x12, 0x22, 0x7e, 0x38, 0x5d, 0x6c, 0x68, 0x99, 0x6f, 0x51, 0x48, 0x92,
+	0x83, 0x25, 0x14, 0x5b, 0x15, 0x53, 0x25, 0x17, 0x99, 0xc9, 0x94, 0x14, 0x1b, 0x27, 0x4a, 0x13,
+	0x8a, 0x7e, 0x7a, 0xd0, 0x77, 0x6b, 0x38, 0x05, 0x2e, 0xa1, 0x73, 0xe4, 0xa0, 0xcb, 0xf8, 0x23,
+	0x80, 0x52, 0xa5, 0x62, 0xbe, 0xdb, 0x08, 0x5d, 0x39, 0xba, 0x0d, 0xc4, 0xce, 0x5f, 0x63, 0x55,
+	0xd4, 0x76, 0xd8, 0x98, 0x6a, 0x84, 0xce, 0x84, 0x1d, 0x56, 0x04, 0x6d, 0x57, 0x73, 0x8f, 0xf0,
+	0x1b, 0x18, 0xd0, 0xc2, 0x33, 0xb5, 0x9c, 0x51, 0x50, 0x04, 0x7e, 0xe8, 0xc5, 0xdd, 0xe9, 0x93,
+	0x71, 0x39, 0x19, 0x1f, 0xf1, 0x1a, 0x93, 0x8a, 0x5f, 0x96, 0xf4, 0x53, 0x58, 0x75, 0xaa, 0xa4,
+	0x9f, 0x36, 0x31, 0xfe, 0x14, 0x86, 0x73, 0x2d, 0x64, 0x7a, 0x97, 0xc9, 0xd5, 0x6c, 0x29, 0x52,
+	0xa3, 0x74, 0xd0, 0xa1, 0x95, 0x07, 0xf7, 0xf8, 0x07, 0x82, 0xf9, 0x33, 0x38, 0xc1, 0xbd, 0xc0,
+	0xc1, 0x49, 0xc8, 0xe2, 0xee, 0x74, 0x68, 0x07, 0x36, 0x35, 0x4f, 0xea, 0x03, 0x3c, 0xdc, 0x1b,
+	0x7b, 0x4a, 0xc4, 0x1e, 0xd8, 0x73, 0x07, 0x57, 0xc9, 0xe8, 0xd1, 0x5b, 0xe0, 0xff, 0xb2, 0xb3,
+	0x16, 0xad, 0xb1, 0xaa, 0x2f, 0xc4, 0x1a, 0xe9, 0x22, 0x95, 0x62, 0xb3, 0xc3, 0xda, 0x6c, 0x4a,
+	0x5e, 0xb7, 0xae, 0xd9, 0xf4, 0x37, 0x83, 0x1e, 0x75, 0xbd, 0x45, 0x5d, 0x66, 0x29, 0xf2, 0x2b,
+	0xf0, 0xe9, 0x7d, 0x71, 0x22, 0xd6, 0x7c, 0x8e, 0xa3, 0x87, 0x0d, 0xc4, 0x79, 0xf6, 0x0a, 0x4e,
+	0xeb, 0x9b, 0xcc, 0xcf, 0xed, 0xe7, 0xbf, 0x1e, 0xd9, 0xe8, 0xe2, 0x18, 0x74, 0x65, 0x57, 0xe0,
+	0xbb, 0xfb, 0xd5, 0x90, 0xbb, 0x31, 0xe4, 0xc8, 0x80, 0x79, 0x87, 0xfe, 0x21, 0x5e, 0xfc, 0x19,
+	0x00, 0xd9, 0xeb, 0x33, 0xd5, 0x34, 0x04, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -236,6 +560,8 @@ type NgramServiceClient interface {
 	Learn(ctx context.Context, in *LearnRequest, opts ...grpc.CallOption) (*LearnResponse, error)
 	// Generate outputs a random string in the trained style.
 	Generate(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (*GenerateResponse, error)
+	// Stats returns statistics about the learned ngrams.
+	Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error)
 }
 
 type ngramServiceClient struct {
@@ -264,12 +590,23 @@ func (c *ngramServiceClient) Generate(ctx context.Context, in *GenerateRequest,
 	return out, nil
 }
 
+func (c *ngramServiceClient) Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error) {
+	out := new(StatsResponse)
+	err := c.cc.Invoke(ctx, "/v1.NgramService/Stats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // NgramServiceServer is the server API for NgramService service.
 type NgramServiceServer interface {
 	// Learn trains the ngram index on a corpus of text.
 	Learn(context.Context, *LearnRequest) (*LearnResponse, error)
 	// Generate outputs a random string in the trained style.
 	Generate(context.Context, *GenerateRequest) (*GenerateResponse, error)
+	// Stats returns statistics about the learned ngrams.
+	Stats(context.Context, *StatsRequest) (*StatsResponse, error)
 }
 
 // UnimplementedNgramServiceServer can be embedded to have forward compatible implementations.
@@ -282,6 +619,9 @@ func (*UnimplementedNgramServiceServer) Learn(ctx context.Context, req *LearnReq
 func (*UnimplementedNgramServiceServer) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Generate not implemented")
 }
+func (*UnimplementedNgramServiceServer) Stats(ctx context.Context, req *StatsRequest) (*StatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stats not implemented")
+}
 
 func RegisterNgramServiceServer(s *grpc.Server, srv NgramServiceServer) {
 	s.RegisterService(&_NgramService_serviceDesc, srv)
@@ -323,6 +663,24 @@ func _NgramService_Generate_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _NgramService_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NgramServiceServer).Stats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/v1.NgramService/Stats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NgramServiceServer).Stats(ctx, req.(*StatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _NgramService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "v1.NgramService",
 	HandlerType: (*NgramServiceServer)(nil),
@@ -335,6 +693,10 @@ var _NgramService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Generate",
 			Handler:    _NgramService_Generate_Handler,
 		},
+		{
+			MethodName: "Stats",
+			Handler:    _NgramService_Stats_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "v1/ngrams.proto",
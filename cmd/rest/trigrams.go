@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"strconv"
@@ -14,6 +16,7 @@ import (
 	"time"
 
 	"github.com/mochi-co/ngrams"
+	"github.com/mochi-co/ngrams/stores"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
@@ -23,6 +26,10 @@ import (
 var (
 	// index is the ngrams index, which will store and tokenize ngrams.
 	index *ngrams.Index
+
+	// errInvalidTop indicates that the top query param of a stats request
+	// wasn't a non-negative integer.
+	errInvalidTop = errors.New("top must be a non-negative integer")
 )
 
 // main is our entrypoint for the service.
@@ -44,10 +51,21 @@ func main() {
 	// variables. We can achieve this in a single-line using one of my favourite new
 	// packages; `envy`, which takes all the flags and transmutes them into env vars.
 	port := flag.Int("port", 8080, "port to serve webserver on")
+	store := flag.String("store", "", "file to persist ngrams to; kept in memory if blank")
 	envy.Parse("NGRAMS") // Expose environment variables as NGRAMS_PORT, etc.
 
-	// Configure the Ngrams indexer to index trigrams and use the default options.
-	index = ngrams.NewIndex(3, nil)
+	// Configure the Ngrams indexer to index trigrams, persisting them to a file
+	// store if one was given so they survive restarts.
+	o := new(ngrams.Options)
+	if *store != "" {
+		fs, err := stores.NewFileStore(*store)
+		if err != nil {
+			log.Fatalf("failed to open store: %v", err)
+		}
+		o.Store = fs
+	}
+
+	index = ngrams.NewIndex(3, o)
 
 	// Setup our basic webserver; we'll use Chi here because it's a little bit
 	// simpler than implementing a pure net/http design, has some convenient and
@@ -59,9 +77,11 @@ func main() {
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
-	// Our mini REST router which points to the learn and generate endpoints.
+	// Our mini REST router which points to the learn, generate and stats
+	// endpoints.
 	r.Post("/learn", learnHandler)
 	r.Get("/generate", generateHandler)
+	r.Get("/stats", statsHandler)
 
 	// We'll use the stdlib http to actually run the chi server so we can
 	// implement our own signal catching. There's a way to do this using chi's
@@ -96,8 +116,14 @@ func learnHandler(w http.ResponseWriter, r *http.Request) {
 		errHandler(w, 400, err)
 	}
 
-	tokens, err := index.Parse(string(b))
+	// Parsing stops if the request is cancelled or times out, in which case the
+	// timeout middleware responds instead.
+	tokens, err := index.ParseContext(r.Context(), string(b))
 	if err != nil {
+		if cancelled(err) {
+			return
+		}
+
 		errHandler(w, 500, err)
 	}
 
@@ -113,7 +139,8 @@ func learnHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // generateHandler is a GET request handler that generates a string of random
-// text in the syntactic style of the trained ngrams.
+// text in the syntactic style of the trained ngrams. The sampling controls
+// temperature, top_k, top_p and greedy can be set as query params.
 func generateHandler(w http.ResponseWriter, r *http.Request) {
 	var err error
 
@@ -127,8 +154,18 @@ func generateHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	out, err := index.Babble("", tokenLen) // Starting seed is left blank for random choice.
+	o, err := generateOptions(r.URL.Query())
 	if err != nil {
+		errHandler(w, 400, err)
+		return
+	}
+
+	out, err := index.GenerateContext(r.Context(), "", tokenLen, o) // Starting seed is left blank for random choice.
+	if err != nil {
+		if cancelled(err) {
+			return
+		}
+
 		if err == ngrams.ErrEmptyIndex {
 			m, err := json.Marshal(map[string]interface{}{
 				"err": "index is empty; please learn ngrams before generating.",
@@ -141,6 +178,11 @@ func generateHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if err == ngrams.ErrInvalidSampling {
+			errHandler(w, 400, err)
+			return
+		}
+
 		errHandler(w, 500, err)
 	}
 
@@ -156,8 +198,87 @@ func generateHandler(w http.ResponseWriter, r *http.Request) {
 
 }
 
-// errHandler is a convenience function which writes and logs errors.
+// statsHandler is a GET request handler that returns statistics about the
+// learned ngrams. The number of most frequent ngrams returned can be set with
+// the top query param (default 10).
+func statsHandler(w http.ResponseWriter, r *http.Request) {
+	top := 10
+	if r.URL.Query().Get("top") != "" {
+		var err error
+		top, err = strconv.Atoi(r.URL.Query().Get("top"))
+		if err != nil || top < 0 {
+			errHandler(w, 400, errInvalidTop)
+			return
+		}
+	}
+
+	s, err := index.StatsTop(top)
+	if err != nil {
+		errHandler(w, 500, err)
+		return
+	}
+
+	m, err := json.Marshal(s)
+	if err != nil {
+		errHandler(w, 500, err)
+		return
+	}
+
+	w.Write(m)
+
+}
+
+// generateOptions returns the generate options set in the query params.
+func generateOptions(q url.Values) (o *ngrams.GenerateOptions, err error) {
+	o = new(ngrams.GenerateOptions)
+
+	if q.Get("temperature") != "" {
+		o.Temperature, err = strconv.ParseFloat(q.Get("temperature"), 64)
+		if err != nil {
+			return
+		}
+	}
+
+	if q.Get("top_k") != "" {
+		o.TopK, err = strconv.Atoi(q.Get("top_k"))
+		if err != nil {
+			return
+		}
+	}
+
+	if q.Get("top_p") != "" {
+		o.TopP, err = strconv.ParseFloat(q.Get("top_p"), 64)
+		if err != nil {
+			return
+		}
+	}
+
+	if q.Get("greedy") != "" {
+		o.Greedy, err = strconv.ParseBool(q.Get("greedy"))
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// cancelled returns true if an error was caused by the request context being
+// cancelled or timing out.
+func cancelled(err error) bool {
+	return err == context.Canceled || err == context.DeadlineExceeded
+}
+
+// errHandler is a convenience function which writes and logs errors. The
+// message of an error caused by the request is included in the response, so
+// the client can tell what was wrong with it.
 func errHandler(w http.ResponseWriter, code int, err error) {
 	log.Println("Error:", err)
-	http.Error(w, http.StatusText(code), code)
+
+	msg := http.StatusText(code)
+	if code < 500 && err != nil {
+		msg += ": " + err.Error()
+	}
+
+	http.Error(w, msg, code)
 }
@@ -0,0 +1,237 @@
+package ngrams
+
+import (
+	"errors"
+	"strings"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+const (
+
+	// maxConstraintAttempts is the number of times generation will be
+	// attempted before giving up on satisfying the required phrases.
+	maxConstraintAttempts = 10
+
+	// maxRestartAttempts is the number of random ngrams which will be tried
+	// when no variations of the current ngram satisfy the constraints.
+	maxRestartAttempts = 100
+)
+
+var (
+	// ErrConstraintsUnmet indicates that no text could be generated which
+	// satisfied the generation constraints.
+	ErrConstraintsUnmet = errors.New("could not satisfy constraints")
+)
+
+// Constraints restrict the content of generated text. Tokens which would
+// break a constraint are removed from the variations before a token is
+// selected.
+type Constraints struct {
+
+	// Banned contains tokens or phrases which must never be generated. Phrases
+	// are split into tokens using the index's tokenizer, and are matched
+	// without regard to case.
+	Banned []string
+
+	// Required contains tokens or phrases which must appear in the generated
+	// text. They are tokenized and matched in the same way as Banned. The
+	// next token of a missing phrase is always selected when it can follow the
+	// preceding tokens, but nothing steers generation towards contexts it can
+	// follow, so generation is retried up to 10 times if any are still missing
+	// at the end, after which ErrConstraintsUnmet is returned.
+	Required []string
+
+	// MaxRepeat is the maximum number of times any ngram may appear in the
+	// generated text. 0 is unlimited.
+	MaxRepeat int
+
+	// MaxVerbatim is the maximum number of consecutive tokens which may be
+	// generated by following ngrams which were indexed, and so could have been
+	// reproduced verbatim from the training text. Once reached, the next token
+	// must be one which was never indexed after the preceding tokens. If the
+	// index records all orders it is selected from a shorter context, otherwise
+	// generation jumps to a random ngram. 0 is unlimited.
+	MaxVerbatim int
+}
+
+// constrainer tracks the state of the constraints during a generation.
+type constrainer struct {
+
+	// c contains the constraints being applied.
+	c *Constraints
+
+	// n is the length of the ngrams counted for MaxRepeat.
+	n int
+
+	// banned contains the tokenized banned phrases.
+	banned [][]string
+
+	// required contains the tokenized required phrases which haven't been
+	// generated yet.
+	required [][]string
+
+	// counts contains the number of times each ngram has been generated.
+	counts map[string]int
+
+	// run is the number of consecutive tokens generated from indexed ngrams.
+	run int
+}
+
+// newConstrainer returns a constrainer for a generation from the index which
+// begins with the seed tokens.
+func (i *Index) newConstrainer(c *Constraints, seed []string) *constrainer {
+	k := &constrainer{
+		c:      c,
+		n:      i.N,
+		counts: make(map[string]int),
+	}
+
+	for _, b := range c.Banned {
+		if t := i.Tokenizer.Tokenize(b); len(t) > 0 {
+			k.banned = append(k.banned, t)
+		}
+	}
+
+	for _, r := range c.Required {
+		if t := i.Tokenizer.Tokenize(r); len(t) > 0 {
+			k.required = append(k.required, t)
+		}
+	}
+
+	for j := range seed {
+		k.accept(seed[:j], seed[j], false)
+	}
+
+	return k
+}
+
+// breaking returns true if the next token must not continue an indexed ngram.
+func (k *constrainer) breaking() bool {
+	return k.c.MaxVerbatim > 0 && k.run >= k.c.MaxVerbatim
+}
+
+// filter returns the variations which can follow the output without breaking
+// any of the constraints. Any tokens in exclude are also removed.
+func (k *constrainer) filter(out []string, v stores.Variations, exclude stores.Variations) stores.Variations {
+	f := make(stores.Variations, len(v))
+	for token, n := range v {
+		if _, ok := exclude[token]; ok {
+			continue
+		}
+
+		if token != SentenceEnd && !k.allowed(out, token) {
+			continue
+		}
+
+		f[token] = n
+	}
+
+	// Always take the opportunity to continue a missing required phrase,
+	// preferring the tokens which get furthest through one.
+	r := make(stores.Variations)
+	var best int
+	for token, n := range f {
+		p := k.progress(out, token)
+		if p == 0 || p < best {
+			continue
+		}
+
+		if p > best {
+			best = p
+			r = make(stores.Variations)
+		}
+		r[token] = n
+	}
+
+	if len(r) > 0 {
+		return r
+	}
+
+	return f
+}
+
+// allowed returns true if a token can follow the output.
+func (k *constrainer) allowed(out []string, token string) bool {
+	for _, b := range k.banned {
+		if strings.EqualFold(token, b[len(b)-1]) && hasSuffixFold(out, b[:len(b)-1]) {
+			return false
+		}
+	}
+
+	if k.c.MaxRepeat > 0 && k.counts[k.ngram(out, token)] >= k.c.MaxRepeat {
+		return false
+	}
+
+	return true
+}
+
+// progress returns the number of tokens of a missing required phrase which
+// the output would end with if a token followed it, either by starting the
+// phrase or by continuing the part of it the output already ends with. If the
+// token is in no missing phrase, 0 is returned.
+func (k *constrainer) progress(out []string, token string) int {
+	var best int
+	for _, r := range k.required {
+		for m := len(r) - 1; m >= best; m-- {
+			if strings.EqualFold(token, r[m]) && hasSuffixFold(out, r[:m]) {
+				best = m + 1
+				break
+			}
+		}
+	}
+
+	return best
+}
+
+// accept records a token which was added to the output. Indexed indicates the
+// token followed an indexed ngram.
+func (k *constrainer) accept(out []string, token string, indexed bool) {
+	generated := append(out[:len(out):len(out)], token)
+	missing := k.required[:0]
+	for _, r := range k.required {
+		if !hasSuffixFold(generated, r) {
+			missing = append(missing, r)
+		}
+	}
+	k.required = missing
+
+	k.counts[k.ngram(out, token)]++
+
+	if indexed {
+		k.run++
+	} else {
+		k.run = 0
+	}
+}
+
+// satisfied returns true if all of the required phrases have been generated.
+func (k *constrainer) satisfied() bool {
+	return len(k.required) == 0
+}
+
+// ngram returns the key of the ngram ending with a token following the output.
+func (k *constrainer) ngram(out []string, token string) string {
+	if len(out) > k.n-1 {
+		out = out[len(out)-(k.n-1):]
+	}
+
+	return stores.JoinKey(append(append(make([]string, 0, k.n), out...), token))
+}
+
+// hasSuffixFold returns true if a slice of tokens ends with another, ignoring
+// case.
+func hasSuffixFold(tokens, suffix []string) bool {
+	if len(suffix) > len(tokens) {
+		return false
+	}
+
+	offset := len(tokens) - len(suffix)
+	for j := range suffix {
+		if !strings.EqualFold(tokens[offset+j], suffix[j]) {
+			return false
+		}
+	}
+
+	return true
+}
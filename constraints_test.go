@@ -0,0 +1,188 @@
+package ngrams
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+// generatedTokens returns the lowercased tokens of generated text.
+func generatedTokens(i *Index, b string) []string {
+	return i.Tokenizer.Tokenize(strings.ToLower(b))
+}
+
+func TestConstrainerFilter(t *testing.T) {
+	i := NewIndex(3, nil)
+	k := i.newConstrainer(&Constraints{
+		Banned:    []string{"cat", "sat on"},
+		Required:  []string{"dog"},
+		MaxRepeat: 1,
+	}, []string{"the", "mat"})
+
+	v := stores.Variations{"cat": 1, "on": 2, "mat": 1, SentenceEnd: 1}
+	require.Equal(t, stores.Variations{"on": 2, "mat": 1, SentenceEnd: 1}, k.filter([]string{"a"}, v, nil))
+	require.Equal(t, stores.Variations{"mat": 1, SentenceEnd: 1}, k.filter([]string{"the", "mat", "sat"}, v, nil))
+
+	// The ngram "the mat" has already been generated once.
+	require.Equal(t, stores.Variations{"on": 2, SentenceEnd: 1}, k.filter([]string{"the"}, v, nil))
+
+	// Excluded tokens are removed.
+	require.Equal(t, stores.Variations{"on": 2}, k.filter([]string{"a"}, v, stores.Variations{"mat": 1, SentenceEnd: 1}))
+
+	// Missing required tokens are always selected when available.
+	v["dog"] = 1
+	require.Equal(t, stores.Variations{"dog": 1}, k.filter([]string{"a"}, v, nil))
+	require.False(t, k.satisfied())
+	k.accept([]string{"a"}, "dog", true)
+	require.True(t, k.satisfied())
+}
+
+func TestConstrainerPhrases(t *testing.T) {
+	i := NewIndex(3, nil)
+	k := i.newConstrainer(&Constraints{
+		Banned:   []string{"The Cat"},
+		Required: []string{"Sat on, the"},
+	}, nil)
+	require.Equal(t, [][]string{{"Sat", "on", ",", "the"}}, k.required)
+
+	// Phrases are matched without regard to case.
+	v := stores.Variations{"cat": 1, "sat": 1, "on": 1}
+	require.Equal(t, stores.Variations{"sat": 1}, k.filter([]string{"the"}, v, nil))
+	require.Equal(t, stores.Variations{"on": 1}, k.filter([]string{"the", "sat"}, v, nil))
+
+	// The phrase is only generated once all of its tokens are.
+	out := []string{"the", "sat", "on", ","}
+	for j, token := range out {
+		k.accept(out[:j], token, true)
+	}
+	require.False(t, k.satisfied())
+	k.accept(out, "THE", true)
+	require.True(t, k.satisfied())
+}
+
+func TestConstrainerBreaking(t *testing.T) {
+	i := NewIndex(3, nil)
+	k := i.newConstrainer(&Constraints{
+		MaxVerbatim: 2,
+	}, nil)
+
+	k.accept(nil, "a", true)
+	require.False(t, k.breaking())
+	k.accept([]string{"a"}, "b", true)
+	require.True(t, k.breaking())
+	k.accept([]string{"a", "b"}, "c", false)
+	require.False(t, k.breaking())
+}
+
+func TestGenerateConstraintsBanned(t *testing.T) {
+	for _, o := range []*Options{{}, {AllOrders: true}} {
+		o.Rand = rand.New(rand.NewSource(1))
+		i := NewIndex(3, o)
+		i.Parse(sentencesText)
+
+		b, err := i.Generate("the", 40, &GenerateOptions{
+			Constraints: &Constraints{
+				Banned: []string{"cat", "sat on"},
+			},
+		})
+		require.NoError(t, err)
+
+		tokens := generatedTokens(i, b)
+		require.NotContains(t, tokens, "cat")
+		require.NotContains(t, strings.Join(tokens, " "), "sat on")
+	}
+}
+
+func TestGenerateConstraintsRequired(t *testing.T) {
+	for _, o := range []*Options{{}, {AllOrders: true}} {
+		o.Rand = rand.New(rand.NewSource(1))
+		i := NewIndex(3, o)
+		i.Parse(sentencesText)
+
+		b, err := i.Generate("the", 20, &GenerateOptions{
+			Constraints: &Constraints{
+				Required: []string{"dog", "where"},
+			},
+		})
+		require.NoError(t, err)
+
+		tokens := generatedTokens(i, b)
+		require.Contains(t, tokens, "dog")
+		require.Contains(t, tokens, "where")
+
+		// Required phrases are tokenized, and matched in any case.
+		b, err = i.Generate("the", 20, &GenerateOptions{
+			Constraints: &Constraints{
+				Required: []string{"Dog Sat"},
+			},
+		})
+		require.NoError(t, err)
+		require.Contains(t, strings.Join(generatedTokens(i, b), " "), "dog sat")
+
+		_, err = i.Generate("the", 20, &GenerateOptions{
+			Constraints: &Constraints{
+				Required: []string{"unseen"},
+			},
+		})
+		require.Equal(t, ErrConstraintsUnmet, err)
+	}
+}
+
+func TestGenerateConstraintsMaxRepeat(t *testing.T) {
+	for _, o := range []*Options{{}, {AllOrders: true}} {
+		o.Rand = rand.New(rand.NewSource(1))
+		i := NewIndex(2, o)
+		i.Parse(sentencesText)
+
+		b, err := i.Generate("the", 10, &GenerateOptions{
+			Constraints: &Constraints{
+				MaxRepeat: 1,
+			},
+		})
+		require.NoError(t, err)
+
+		tokens := generatedTokens(i, b)
+		seen := make(map[string]bool)
+		for j := 1; j < len(tokens); j++ {
+			if tokens[j] == "." {
+				continue
+			}
+
+			bigram := tokens[j-1] + " " + tokens[j]
+			require.False(t, seen[bigram], bigram)
+			seen[bigram] = true
+		}
+	}
+}
+
+func TestGenerateConstraintsMaxVerbatim(t *testing.T) {
+	i := NewIndex(3, &Options{
+		AllOrders: true,
+		Rand:      rand.New(rand.NewSource(1)),
+	})
+	i.Parse(sentencesText)
+
+	b, err := i.Generate("the cat", 30, &GenerateOptions{
+		Constraints: &Constraints{
+			MaxVerbatim: 2,
+		},
+	})
+	require.NoError(t, err)
+
+	// No more than 2 consecutive tokens may follow an indexed trigram.
+	tokens := generatedTokens(i, b)
+	var run int
+	for j := 2; j < len(tokens); j++ {
+		if i.Count(tokens[j-2], tokens[j-1], tokens[j]) > 0 {
+			run++
+		} else {
+			run = 0
+		}
+
+		require.True(t, run <= 2, b)
+	}
+}
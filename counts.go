@@ -0,0 +1,246 @@
+package ngrams
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+const (
+
+	// CountsTSV is the format of count files with one ngram per line, its
+	// tokens separated by spaces, followed by a tab and the number of times
+	// it occurred: "w1 w2 w3\tcount".
+	CountsTSV CountFormat = iota
+
+	// GoogleBooks is the format of the Google Books Ngram datasets. Version 2
+	// files have one line per ngram and year: "w1 w2 w3\tyear\tmatch_count\t
+	// volume_count". Version 3 files have one line per ngram, followed by a
+	// tab separated "year,match_count,volume_count" for each year it occurred.
+	// Both versions are read, and the match counts of every year in range are
+	// summed. The _START_ and _END_ tokens are read as sentence markers. The
+	// index precedes each sentence with N-1 start markers, so ngrams which
+	// begin with _START_ are also added preceded by each number of extra
+	// markers up to N-1, matching the ngrams Parse indexes.
+	GoogleBooks
+)
+
+var (
+	// ErrInvalidCounts indicates that a count file is not in the given format,
+	// or that the format is unknown.
+	ErrInvalidCounts = errors.New("invalid ngram counts")
+)
+
+// CountFormat is the layout of a file of pre-counted ngrams.
+type CountFormat int
+
+// CountOptions controls how a file of pre-counted ngrams is read.
+type CountOptions struct {
+
+	// Format is the layout of the file.
+	Format CountFormat
+
+	// MinYear excludes Google Books counts from before the year. 0 includes
+	// every year.
+	MinYear int
+
+	// MaxYear excludes Google Books counts from after the year. 0 includes
+	// every year.
+	MaxYear int
+}
+
+// ReadCounts reads a file of pre-counted ngrams, adding each to the store with
+// its count rather than counting it from text, and returns the number of lines
+// which were added. The tokens are used as they are, without the tokenizer. If
+// the index records all orders, ngrams of every order up to N are added at
+// their own order, so each order should be in the file; otherwise only ngrams
+// of exactly N tokens are added. Other ngrams are skipped. Stores which
+// implement stores.CountAdder add each count at once, otherwise the ngram is
+// added count times.
+func (i *Index) ReadCounts(r io.Reader, o CountOptions) (int, error) {
+	return i.ReadCountsContext(context.Background(), r, o)
+}
+
+// ReadCountsContext reads a file of pre-counted ngrams into the index, as per
+// ReadCounts. Reading stops with the context's error if it is cancelled,
+// leaving any counts which were already read in the store.
+func (i *Index) ReadCountsContext(ctx context.Context, r io.Reader, o CountOptions) (int, error) {
+	var parse func(string, CountOptions) ([]string, int64, error)
+	switch o.Format {
+	case CountsTSV:
+		parse = parseCountsTSV
+	case GoogleBooks:
+		parse = parseGoogleBooks
+	default:
+		return 0, ErrInvalidCounts
+	}
+
+	var added int
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		err := ctx.Err()
+		if err != nil {
+			return added, err
+		}
+
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		tokens, count, err := parse(line, o)
+		if err != nil {
+			return added, err
+		}
+
+		ok, err := i.addCount(tokens, count)
+		if err != nil {
+			return added, err
+		}
+
+		if o.Format == GoogleBooks {
+			for _, padded := range i.padSentenceStart(tokens) {
+				p, err := i.addCount(padded, count)
+				if err != nil {
+					return added, err
+				}
+				ok = ok || p
+			}
+		}
+
+		if ok {
+			added++
+		}
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return added, err
+	}
+
+	return added, nil
+}
+
+// addCount adds an ngram to the store count times, returning false if the
+// ngram isn't of an order the index records. Sentence start markers are only
+// ever context, so are never added as a future.
+func (i *Index) addCount(tokens []string, count int64) (bool, error) {
+	n := len(tokens)
+	if n == 0 || count < 1 || n > i.N || (n < i.N && !i.allOrders()) {
+		return false, nil
+	}
+
+	if tokens[n-1] == SentenceStart {
+		return false, nil
+	}
+
+	err := stores.AddN(i.Store, stores.JoinKey(tokens[:n-1]), tokens[n-1], count)
+	if err != nil {
+		return false, err
+	}
+
+	if n > 1 && i.continuations() {
+		err = stores.AddN(i.Store, continuationKey(tokens[1:]), tokens[0], count)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// padSentenceStart returns the ngrams which an ngram beginning with a single
+// sentence start marker also stands for, preceded by each number of extra
+// start markers until there are N-1, as the index precedes every sentence.
+func (i *Index) padSentenceStart(tokens []string) [][]string {
+	if len(tokens) < 2 || tokens[0] != SentenceStart || tokens[1] == SentenceStart {
+		return nil
+	}
+
+	var padded [][]string
+	for n := len(tokens) + 1; n <= i.N; n++ {
+		p := make([]string, n)
+		for j := 0; j < n-len(tokens); j++ {
+			p[j] = SentenceStart
+		}
+		copy(p[n-len(tokens):], tokens)
+		padded = append(padded, p)
+	}
+
+	return padded
+}
+
+// parseCountsTSV parses a line of a CountsTSV file.
+func parseCountsTSV(line string, o CountOptions) ([]string, int64, error) {
+	j := strings.LastIndexByte(line, '\t')
+	if j == -1 {
+		return nil, 0, ErrInvalidCounts
+	}
+
+	count, err := strconv.ParseInt(strings.TrimSpace(line[j+1:]), 10, 64)
+	if err != nil || count < 0 {
+		return nil, 0, ErrInvalidCounts
+	}
+
+	return strings.Fields(line[:j]), count, nil
+}
+
+// parseGoogleBooks parses a line of a version 2 or 3 Google Books Ngram file,
+// summing the match counts of the years within the range of the options.
+func parseGoogleBooks(line string, o CountOptions) ([]string, int64, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) < 2 {
+		return nil, 0, ErrInvalidCounts
+	}
+
+	tokens := strings.Fields(fields[0])
+	for j, t := range tokens {
+		switch t {
+		case "_START_":
+			tokens[j] = SentenceStart
+		case "_END_":
+			tokens[j] = SentenceEnd
+		}
+	}
+
+	// Version 2 lines have a year and counts in separate fields, while version
+	// 3 lines have a comma separated year and counts for every year.
+	years := fields[1:]
+	if !strings.Contains(fields[1], ",") {
+		if len(fields) < 3 {
+			return nil, 0, ErrInvalidCounts
+		}
+		years = []string{fields[1] + "," + fields[2]}
+	}
+
+	var count int64
+	for _, y := range years {
+		parts := strings.Split(y, ",")
+		if len(parts) < 2 {
+			return nil, 0, ErrInvalidCounts
+		}
+
+		year, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, 0, ErrInvalidCounts
+		}
+
+		n, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || n < 0 {
+			return nil, 0, ErrInvalidCounts
+		}
+
+		if (o.MinYear != 0 && year < o.MinYear) || (o.MaxYear != 0 && year > o.MaxYear) {
+			continue
+		}
+
+		count += n
+	}
+
+	return tokens, count, nil
+}
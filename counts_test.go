@@ -0,0 +1,139 @@
+package ngrams
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+func TestReadCounts(t *testing.T) {
+	i := NewIndex(3, nil)
+	n, err := i.ReadCounts(strings.NewReader("to be or\t3\nto be that\t1\r\n\nbe or not\t2\nto be\t5\n"), CountOptions{})
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	require.Equal(t, int64(3), i.Count("to", "be", "or"))
+	require.Equal(t, int64(1), i.Count("to", "be", "that"))
+	require.Equal(t, int64(2), i.Count("be", "or", "not"))
+
+	// Bigrams are skipped if the index only records ngrams of N tokens.
+	require.Equal(t, int64(0), i.Count("to", "be"))
+}
+
+func TestReadCountsMatchesParse(t *testing.T) {
+	for _, o := range []Options{
+		{},
+		{AllOrders: true},
+		{Smoother: NewKneserNey(0.75)},
+		{Store: stores.NewCompactStore()},
+		{Store: &plainStore{stores.NewMemoryStore()}},
+	} {
+		parsed := NewIndex(3, &Options{AllOrders: o.AllOrders, Smoother: o.Smoother})
+		_, err := parsed.Parse(smoothingText)
+		require.NoError(t, err)
+
+		// Writing out the counts of every order and reading them back gives
+		// the same index, including the continuation counts.
+		var b strings.Builder
+		var lines int
+		for key, v := range storeGrams(t, parsed.Store) {
+			if strings.HasPrefix(key, continuationPrefix) {
+				continue
+			}
+
+			for future, c := range v {
+				b.WriteString(strings.Join(append(stores.SplitKey(key), future), " "))
+				b.WriteString("\t" + strconv.FormatInt(c, 10) + "\n")
+				lines++
+			}
+		}
+
+		i := NewIndex(3, &o)
+		n, err := i.ReadCounts(strings.NewReader(b.String()), CountOptions{Format: CountsTSV})
+		require.NoError(t, err)
+		require.Equal(t, lines, n)
+
+		s := i.Store
+		if p, ok := s.(*plainStore); ok {
+			s = p.Store
+		}
+
+		require.Equal(t, storeGrams(t, parsed.Store), storeGrams(t, s))
+	}
+}
+
+func TestReadCountsGoogleBooks(t *testing.T) {
+	i := NewIndex(2, &Options{AllOrders: true})
+
+	v2 := "circumvallate\t1978\t335\t91\ncircumvallate\t1979\t261\t91\n_START_ circumvallate\t1979\t10\t8\n"
+	n, err := i.ReadCounts(strings.NewReader(v2), CountOptions{Format: GoogleBooks})
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Equal(t, int64(596), i.Frequency("circumvallate"))
+	require.Equal(t, int64(10), i.Count(SentenceStart, "circumvallate"))
+
+	i = NewIndex(2, &Options{AllOrders: true})
+	v3 := "very old\t1990,4,2\t2000,6,3\t2010,9,5\nold _END_\t2000,7,7\n_START_\t2000,100,100\n"
+	n, err = i.ReadCounts(strings.NewReader(v3), CountOptions{Format: GoogleBooks, MinYear: 1995, MaxYear: 2005})
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, int64(6), i.Count("very", "old"))
+	require.Equal(t, int64(7), i.Count("old", SentenceEnd))
+
+	// Sentence start markers are only ever context.
+	require.Equal(t, int64(0), i.Frequency(SentenceStart))
+
+	// Ngrams beginning a sentence are also added with as many start markers
+	// as the index precedes each sentence with.
+	for _, o := range []Options{{SentenceMarkers: true}, {SentenceMarkers: true, AllOrders: true}} {
+		i = NewIndex(3, &o)
+		n, err = i.ReadCounts(strings.NewReader("_START_ The\t2000,5,1\n_START_ The cat\t2000,3,1\nThe cat _END_\t2000,3,1\n"), CountOptions{Format: GoogleBooks})
+		require.NoError(t, err)
+		require.Equal(t, 3, n)
+		require.Equal(t, int64(5), i.Count(SentenceStart, SentenceStart, "The"))
+		require.Equal(t, int64(3), i.Count(SentenceStart, "The", "cat"))
+
+		if o.AllOrders {
+			require.Equal(t, int64(5), i.Count(SentenceStart, "The"))
+		}
+
+		// The imported sentences can be generated like parsed ones.
+		b, err := i.BabbleSentences("", 1)
+		require.NoError(t, err)
+		require.Equal(t, "The cat.", b)
+	}
+}
+
+func TestReadCountsInvalid(t *testing.T) {
+	i := NewIndex(3, nil)
+	for _, c := range []struct {
+		data string
+		o    CountOptions
+	}{
+		{"to be or 3\n", CountOptions{}},
+		{"to be or\tthree\n", CountOptions{}},
+		{"to be or\t-1\n", CountOptions{}},
+		{"to be or\t1990\n", CountOptions{Format: GoogleBooks}},
+		{"to be or\tyear\t1\t1\n", CountOptions{Format: GoogleBooks}},
+		{"to be or\t1990,x,1\n", CountOptions{Format: GoogleBooks}},
+		{"to be or\t1990\n", CountOptions{Format: CountFormat(9)}},
+	} {
+		_, err := i.ReadCounts(strings.NewReader(c.data), c.o)
+		require.Equal(t, ErrInvalidCounts, err, c.data)
+	}
+}
+
+func TestReadCountsContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	i := NewIndex(3, nil)
+	n, err := i.ReadCountsContext(ctx, strings.NewReader("to be or\t3\n"), CountOptions{})
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, 0, n)
+}
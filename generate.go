@@ -0,0 +1,474 @@
+package ngrams
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+var (
+	// defaultRand is the source of randomness used when neither the index nor
+	// the generate options provide one.
+	defaultRand = stores.NewLockedRand(time.Now().UnixNano())
+)
+
+// Restart selects how a new ngram is chosen when generation can't continue
+// from the preceding tokens.
+type Restart int
+
+const (
+
+	// RestartUniform chooses any ngram, each with the same probability.
+	RestartUniform Restart = iota
+
+	// RestartWeighted chooses any ngram, with a probability proportional to the
+	// number of times it was indexed. The store must implement
+	// stores.WeightedSampler, otherwise ngrams are chosen uniformly.
+	RestartWeighted
+
+	// RestartSentenceStart chooses an ngram which starts a sentence, with a
+	// probability proportional to the number of sentences it started. The
+	// index must use sentence markers.
+	RestartSentenceStart
+)
+
+// GenerateOptions contains parameters for generating text from the index.
+type GenerateOptions struct {
+
+	// Rand is the source of randomness used to select tokens. If nil, the
+	// index's Rand will be used. Using a source with a fixed seed will always
+	// generate the same output from the same index.
+	Rand *rand.Rand
+
+	// Sentences stops generation once a number of complete sentences have
+	// been generated. The index must use sentence markers.
+	Sentences int
+
+	// Temperature scales the probability of each token before selection.
+	// Values below 1 make likely tokens more likely (more conservative), and
+	// values above 1 flatten the distribution (more creative). 0 is treated
+	// as 1, which leaves the probabilities unchanged.
+	Temperature float64
+
+	// TopK limits selection to the k most likely tokens. 0 is unlimited.
+	TopK int
+
+	// TopP limits selection to the smallest set of the most likely tokens
+	// whose probabilities sum to at least p (nucleus sampling). Must be between
+	// 0 and 1; 0 or 1 is unlimited.
+	TopP float64
+
+	// Greedy always selects the most likely token, ignoring all other
+	// sampling controls. Ties are broken alphabetically.
+	Greedy bool
+
+	// Constraints restrict the content of the generated text, if set.
+	Constraints *Constraints
+
+	// Restart selects how a new ngram is chosen when nothing was indexed
+	// following the preceding tokens. Indexes which record all orders back off
+	// to shorter contexts instead.
+	Restart Restart
+}
+
+// Babble generates a random sequence of up to n ngrams. The future ngrams will be
+// selected based on their probability. The n value total will include discrete
+// punctuation depending on the tokenizer in use. If the index uses sentence
+// markers and the start string is blank, the sequence will begin at the start
+// of a sentence.
+func (i *Index) Babble(start string, n int) (b string, err error) {
+	return i.GenerateContext(context.Background(), start, n, nil)
+}
+
+// BabbleContext generates a random sequence of up to n ngrams, as per Babble.
+// Generation stops with the context's error if it is cancelled.
+func (i *Index) BabbleContext(ctx context.Context, start string, n int) (b string, err error) {
+	return i.GenerateContext(ctx, start, n, nil)
+}
+
+// BabbleSentences generates a random sequence of k complete sentences. If the
+// start string is blank the first sentence will begin at the start of a
+// sentence, otherwise it will continue from the start string. The index must
+// use sentence markers.
+func (i *Index) BabbleSentences(start string, k int) (b string, err error) {
+	return i.GenerateContext(context.Background(), start, 0, &GenerateOptions{
+		Sentences: k,
+	})
+}
+
+// Generate generates a random sequence of up to n ngrams, as per Babble, using
+// the given generation options. If the options request a number of sentences,
+// n may be 0 to generate as many tokens as the sentences need.
+func (i *Index) Generate(start string, n int, o *GenerateOptions) (b string, err error) {
+	return i.GenerateContext(context.Background(), start, n, o)
+}
+
+// GenerateContext generates a random sequence of up to n ngrams, as per
+// Generate. Generation stops with the context's error if it is cancelled.
+func (i *Index) GenerateContext(ctx context.Context, start string, n int, o *GenerateOptions) (b string, err error) {
+	g := &generator{
+		ctx: ctx,
+		i:   i,
+		r:   i.Rand,
+	}
+
+	if o != nil {
+		err = o.validate()
+		if err != nil {
+			return
+		}
+
+		if o.sampling() {
+			g.o = o
+		}
+
+		g.c = o.Constraints
+		g.restart = o.Restart
+		if o.Restart == RestartSentenceStart && !i.SentenceMarkers {
+			return "", ErrNoSentenceMarkers
+		}
+
+		if o.Rand != nil {
+			g.r = o.Rand
+		}
+
+		if o.Sentences > 0 {
+			if !i.SentenceMarkers {
+				return "", ErrNoSentenceMarkers
+			}
+
+			g.sentences = o.Sentences
+			if n <= 0 {
+				n = o.Sentences * maxSentenceTokens
+			}
+		}
+	}
+
+	if g.r == nil {
+		g.r = defaultRand
+	}
+
+	return g.generate(start, n)
+}
+
+// generator holds the state of a single generation.
+type generator struct {
+
+	// ctx is checked for cancellation before each token is generated.
+	ctx context.Context
+
+	// i is the index being generated from.
+	i *Index
+
+	// r is the source of randomness.
+	r *rand.Rand
+
+	// o contains the sampling controls, if any were set.
+	o *GenerateOptions
+
+	// sentences is the number of sentences after which generation stops, or
+	// 0 to never stop at a sentence end.
+	sentences int
+
+	// c contains the generation constraints, if any were set.
+	c *Constraints
+
+	// k tracks the state of the constraints during each attempt.
+	k *constrainer
+
+	// restart selects how a new ngram is chosen.
+	restart Restart
+}
+
+// next selects the next token from a set of variations, using the sampling
+// controls if any were set.
+func (g *generator) next(v stores.Variations) string {
+	if g.o != nil {
+		return g.o.sample(g.r, candidates(v))
+	}
+
+	return v.NextWeightedRandFrom(g.r)
+}
+
+// nextIndexed selects the next token from the variations of a key. If the
+// variations weren't filtered by constraints and no sampling controls were
+// set, the store's cached cumulative weights are used if it has them.
+func (g *generator) nextIndexed(key string, v stores.Variations) string {
+	if g.k == nil && g.o == nil {
+		if s, ok := g.i.Store.(stores.CumulativeStore); ok {
+			if ok, c := s.Cumulative(key); ok {
+				return c.NextFrom(g.r)
+			}
+		}
+	}
+
+	return g.next(v)
+}
+
+// any selects a random ngram from the store, using the generator's source of
+// randomness if the store supports it.
+func (g *generator) any() (string, stores.Variations, error) {
+	switch g.restart {
+	case RestartWeighted:
+		if s, ok := g.i.Store.(stores.WeightedSampler); ok {
+			return s.AnyWeightedFrom(g.r)
+		}
+	case RestartSentenceStart:
+		// The ngrams which start a sentence all follow the sentence start
+		// context, so seeking it selects one weighted by frequency.
+		key := stores.JoinKey(g.i.sentenceStart())
+		if ok, v := g.i.Store.Get(key); ok {
+			return key, v, nil
+		}
+	}
+
+	if s, ok := g.i.Store.(stores.Sampler); ok {
+		return s.AnyFrom(g.r)
+	}
+
+	return g.i.Store.Any()
+}
+
+// generate generates a random sequence of up to n ngrams following the start
+// string, stopping early if the number of sentences is reached. If there are
+// constraints, generation is retried until the required tokens are included.
+func (g *generator) generate(start string, n int) (string, error) {
+	if g.c == nil {
+		return g.attempt(start, n)
+	}
+
+	for j := 0; j < maxConstraintAttempts; j++ {
+		b, err := g.attempt(start, n)
+		if err != nil && err != ErrConstraintsUnmet {
+			return "", err
+		}
+
+		if err == nil && g.k.satisfied() {
+			return b, nil
+		}
+	}
+
+	return "", ErrConstraintsUnmet
+}
+
+// attempt makes a single attempt at generating a sequence of up to n ngrams
+// following the start string.
+func (g *generator) attempt(start string, n int) (b string, err error) {
+	i := g.i
+
+	// We need the start string as the first tokens in the selected output,
+	// otherwise they'll be skipped. If the string is blank, this will start
+	// with an empty slice and immediately seek any ngram, or the start of a
+	// sentence if sentence markers are in use.
+	o := i.Tokenizer.Tokenize(start)
+	start = stores.JoinKey(o)
+	if len(o) == 0 && i.SentenceMarkers {
+		start = stores.JoinKey(i.sentenceStart())
+	}
+
+	if g.c != nil {
+		g.k = i.newConstrainer(g.c, o)
+	}
+
+	// If the index records all orders, unseen contexts back off to
+	// progressively shorter contexts rather than jumping to a random ngram.
+	if i.allOrders() {
+		return g.generateBackoff(o, n)
+	}
+
+	// For however many tokens we want to use, we'll range through looking
+	// for matching ngram keys.
+	var ended int
+	for j := 0; j < n; j++ {
+		err = g.ctx.Err()
+		if err != nil {
+			return "", err
+		}
+
+		r, v, indexed, err := g.seek(start, o)
+		if err != nil {
+			return "", err
+		}
+
+		// Get the next ngram using a weighted random selection from the variations.
+		next := g.nextIndexed(r.Key, v)
+
+		// At the end of each sentence, start again from the start of a new one.
+		if next == SentenceEnd {
+			ended++
+			if ended == g.sentences {
+				break
+			}
+
+			start = stores.JoinKey(i.sentenceStart())
+			continue
+		}
+
+		if g.k != nil {
+			g.k.accept(o, next, indexed)
+		}
+
+		start = stores.JoinKey([]string{r.Prefix, next})
+		if next != "" {
+			o = append(o, next)
+		}
+	}
+
+	// Output the string using the token formatter for the in-use tokenizer.
+	b = i.Tokenizer.Format(o)
+	return
+}
+
+// seek returns the result for the start string and the variations which can
+// follow the output. If there are none, a new ngram is picked at random and
+// indexed is false, as the selected token won't follow the preceding output.
+func (g *generator) seek(start string, out []string) (r *Result, v stores.Variations, indexed bool, err error) {
+	i := g.i
+	ok, r := i.Seek(start)
+	if ok && r != nil && g.k != nil {
+		if !g.k.breaking() {
+			if v = g.k.filter(out, r.Next, nil); len(v) > 0 {
+				return r, v, true, nil
+			}
+		}
+
+		ok = false
+	}
+
+	if ok {
+		if r == nil {
+			return nil, nil, false, ErrNoResult
+		}
+
+		return r, r.Next, true, nil
+	}
+
+	// If nothing was found for the ngram, pick a new ngram at random. When
+	// constrained, keep picking until one has a variation which is allowed.
+	attempts := 1
+	if g.k != nil {
+		attempts = maxRestartAttempts
+	}
+
+	for j := 0; j < attempts; j++ {
+		k, _, err := g.any()
+		if err != nil {
+			return nil, nil, false, err
+		}
+
+		if k == "" {
+			return nil, nil, false, ErrEmptyIndex
+		}
+
+		_, r = i.Seek(k)
+		if r == nil {
+			return nil, nil, false, ErrNoResult
+		}
+
+		if g.k == nil {
+			return r, r.Next, false, nil
+		}
+
+		if v = g.k.filter(out, r.Next, nil); len(v) > 0 {
+			return r, v, false, nil
+		}
+	}
+
+	return nil, nil, false, ErrConstraintsUnmet
+}
+
+// generateBackoff generates a sequence of up to n tokens following the seed
+// tokens, selecting each future from the longest indexed suffix of the
+// preceding tokens.
+func (g *generator) generateBackoff(o []string, n int) (b string, err error) {
+	i := g.i
+	context := append([]string{}, o...)
+	if len(context) == 0 && i.SentenceMarkers {
+		context = i.sentenceStart()
+	}
+
+	var ended int
+	for j := 0; j < n; j++ {
+		err = g.ctx.Err()
+		if err != nil {
+			return "", err
+		}
+
+		key, v, indexed, err := g.backoff(context, o)
+		if err != nil {
+			return "", err
+		}
+
+		next := g.nextIndexed(key, v)
+		if next == SentenceEnd {
+			ended++
+			if ended == g.sentences {
+				break
+			}
+
+			context = i.sentenceStart()
+			continue
+		}
+
+		if g.k != nil {
+			_, ok := indexed[next]
+			g.k.accept(o, next, ok)
+		}
+
+		o = append(o, next)
+		context = i.trimContext(append(context, next))
+	}
+
+	b = i.Tokenizer.Format(o)
+	return
+}
+
+// backoff returns the key and variations of the longest suffix of the context
+// which can follow the output, along with the variations of the full context
+// if it was indexed with N-1 tokens. If there are constraints, the variations
+// are filtered and no key is returned.
+func (g *generator) backoff(context, out []string) (key string, v, indexed stores.Variations, err error) {
+	i := g.i
+	if g.k == nil {
+		var c []string
+		c, v = i.backoff(context)
+		if v == nil {
+			return "", nil, nil, ErrEmptyIndex
+		}
+
+		return stores.JoinKey(c), v, nil, nil
+	}
+
+	// When the verbatim limit is reached, the futures of the full context are
+	// excluded so that the selected token breaks the run.
+	context = i.trimContext(context)
+	var exclude stores.Variations
+	found := false
+	for j := 0; j <= len(context); j++ {
+		ok, lv := i.Lookup(context[j:])
+		if !ok || len(lv) == 0 {
+			continue
+		}
+
+		found = true
+		if j == 0 && len(context) == i.N-1 {
+			indexed = lv
+			if g.k.breaking() {
+				exclude = lv
+				continue
+			}
+		}
+
+		if v = g.k.filter(out, lv, exclude); len(v) > 0 {
+			return "", v, indexed, nil
+		}
+	}
+
+	if !found {
+		return "", nil, nil, ErrEmptyIndex
+	}
+
+	return "", nil, nil, ErrConstraintsUnmet
+}
@@ -0,0 +1,184 @@
+package ngrams
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+func TestGenerateSeeded(t *testing.T) {
+	for _, o := range []*Options{
+		{},
+		{AllOrders: true},
+		{SentenceMarkers: true},
+	} {
+		var outputs []string
+		for j := 0; j < 3; j++ {
+			o.Rand = rand.New(rand.NewSource(42))
+			i := NewIndex(3, o)
+			i.Parse(sentencesText)
+
+			// The unseen start forces a random ngram to be selected from the store.
+			b, err := i.Babble("unseen start", 30)
+			require.NoError(t, err)
+			outputs = append(outputs, b)
+		}
+
+		require.Equal(t, outputs[0], outputs[1])
+		require.Equal(t, outputs[0], outputs[2])
+	}
+}
+
+func TestGenerateOptionsRand(t *testing.T) {
+	i := NewIndex(3, &Options{
+		Rand: rand.New(rand.NewSource(1)),
+	})
+	i.Parse(sentencesText)
+
+	a, err := i.Generate("the cat", 20, &GenerateOptions{
+		Rand: rand.New(rand.NewSource(7)),
+	})
+	require.NoError(t, err)
+
+	b, err := i.Generate("the cat", 20, &GenerateOptions{
+		Rand: rand.New(rand.NewSource(7)),
+	})
+	require.NoError(t, err)
+	require.Equal(t, a, b)
+
+	// Concurrent generators can each use their own source.
+	var wg sync.WaitGroup
+	results := make([]string, 4)
+	for j := range results {
+		wg.Add(1)
+		go func(j int) {
+			defer wg.Done()
+			results[j], _ = i.Generate("the cat", 20, &GenerateOptions{
+				Rand: rand.New(rand.NewSource(7)),
+			})
+		}(j)
+	}
+	wg.Wait()
+
+	for _, r := range results {
+		require.Equal(t, a, r)
+	}
+}
+
+func TestGenerateSentences(t *testing.T) {
+	i := NewIndex(3, nil)
+	i.Parse(sentencesText)
+	_, err := i.Generate("", 10, &GenerateOptions{
+		Sentences: 1,
+	})
+	require.Equal(t, ErrNoSentenceMarkers, err)
+
+	i = NewIndex(3, &Options{
+		SentenceMarkers: true,
+	})
+	i.Parse(sentencesText)
+
+	// The token limit still applies when generating sentences.
+	b, err := i.Generate("", 2, &GenerateOptions{
+		Sentences: 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(i.Tokenizer.Tokenize(b))) // 2 generated, and a full stop from Format.
+}
+
+func TestGenerateContext(t *testing.T) {
+	for _, o := range []*Options{{}, {AllOrders: true}} {
+		i := NewIndex(3, o)
+		i.Parse(sentencesText)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		b, err := i.BabbleContext(ctx, "the cat", 10)
+		require.NoError(t, err)
+		require.NotEmpty(t, b)
+
+		cancel()
+		_, err = i.BabbleContext(ctx, "the cat", 10)
+		require.Equal(t, context.Canceled, err)
+
+		_, err = i.GenerateContext(ctx, "the cat", 10, &GenerateOptions{
+			Constraints: &Constraints{
+				Banned: []string{"dog"},
+			},
+		})
+		require.Equal(t, context.Canceled, err)
+	}
+}
+
+func TestGenerateRestart(t *testing.T) {
+	i := NewIndex(3, &Options{
+		SentenceMarkers: true,
+		Rand:            rand.New(rand.NewSource(1)),
+	})
+	i.Parse(sentencesText)
+
+	// The unseen start forces a new ngram to be chosen, which must start a
+	// sentence.
+	for j := 0; j < 20; j++ {
+		b, err := i.Generate("unseen start", 1, &GenerateOptions{
+			Restart: RestartSentenceStart,
+		})
+		require.NoError(t, err)
+		require.Contains(t, []string{"the", "where"}, generatedTokens(i, b)[2])
+	}
+
+	b, err := i.Generate("unseen start", 10, &GenerateOptions{
+		Restart: RestartWeighted,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, b)
+
+	i = NewIndex(3, nil)
+	i.Parse(sentencesText)
+	_, err = i.Generate("", 10, &GenerateOptions{
+		Restart: RestartSentenceStart,
+	})
+	require.Equal(t, ErrNoSentenceMarkers, err)
+
+	_, err = i.Generate("", 10, &GenerateOptions{
+		Restart: Restart(99),
+	})
+	require.Equal(t, ErrInvalidSampling, err)
+}
+
+// plainStore hides the optional interfaces of a store, except for Sampler.
+type plainStore struct {
+	stores.Store
+}
+
+func (s *plainStore) AnyFrom(r *rand.Rand) (string, stores.Variations, error) {
+	return s.Store.(stores.Sampler).AnyFrom(r)
+}
+
+func TestGenerateCumulative(t *testing.T) {
+	for _, o := range []Options{{}, {AllOrders: true}} {
+		cached := NewIndex(3, &o)
+		cached.Parse(sentencesText)
+
+		// Selecting from the cached cumulative weights must give exactly the
+		// same output as selecting from the variations.
+		plain := NewIndex(3, &o)
+		plain.Store = &plainStore{cached.Store}
+		for seed := int64(0); seed < 10; seed++ {
+			a, err := cached.Generate("the", 30, &GenerateOptions{
+				Rand: rand.New(rand.NewSource(seed)),
+			})
+			require.NoError(t, err)
+
+			b, err := plain.Generate("the", 30, &GenerateOptions{
+				Rand: rand.New(rand.NewSource(seed)),
+			})
+			require.NoError(t, err)
+			require.Equal(t, a, b)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package ngrams
+
+import (
+	"strings"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+// MigrateKeys rewrites the keys of a store which was indexed before keys were
+// escaped by stores.JoinKey, returning the number of keys which were changed.
+// Legacy keys are split into tokens at each space, so only keys containing
+// backslashes or record separators are changed; tokens which contained spaces
+// can't be recovered. The store must implement stores.Iterator, and must only
+// be migrated once, as escaped keys would be escaped again.
+func MigrateKeys(s stores.Store) (int, error) {
+	it, ok := s.(stores.Iterator)
+	if !ok {
+		return 0, ErrNotIterable
+	}
+
+	// The store can't be changed while it's being iterated, so the keys to
+	// change are collected first.
+	var keys []string
+	err := it.Each(func(key string, v stores.Variations) error {
+		if migrateKey(key) != key {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, key := range keys {
+		ok, v := s.Get(key)
+		if !ok {
+			continue
+		}
+
+		for future, count := range v {
+			err = stores.AddN(s, migrateKey(key), future, count)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		err = s.Delete(key)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(keys), nil
+}
+
+// migrateKey returns the escaped key for a legacy key, which joined its tokens
+// with spaces.
+func migrateKey(key string) string {
+	var prefix string
+	if strings.HasPrefix(key, continuationPrefix) {
+		prefix, key = continuationPrefix, key[len(continuationPrefix):]
+	}
+
+	if key == "" {
+		return prefix
+	}
+
+	return prefix + stores.JoinKey(strings.Split(key, " "))
+}
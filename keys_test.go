@@ -0,0 +1,58 @@
+package ngrams
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+func TestMigrateKeys(t *testing.T) {
+	s := stores.NewMemoryStore()
+	s.Add(`a\b c`, "d")
+	s.Add(`a\b c`, "d")
+	s.Add("to be", "or")
+	s.Add(continuationPrefix+"x\x1ey", "z")
+	s.Add("", "to")
+
+	n, err := MigrateKeys(s)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, stores.Grams{
+		`a\\b c`:                    {"d": 2},
+		"to be":                     {"or": 1},
+		continuationPrefix + `x\cy`: {"z": 1},
+		"":                          {"to": 1},
+	}, storeGrams(t, s))
+
+	i := NewIndex(3, &Options{
+		Store:     s,
+		Tokenizer: new(fieldTokenizer),
+	})
+	require.Equal(t, int64(2), i.Count(`a\b`, "c", "d"))
+
+	_, err = MigrateKeys(new(MockStore))
+	require.Equal(t, ErrNotIterable, err)
+}
+
+func TestLoadLegacyKeys(t *testing.T) {
+	i := NewIndex(3, &Options{
+		Tokenizer: new(fieldTokenizer),
+	})
+	i.Store.Add(`a\b c`, "d")
+
+	var b bytes.Buffer
+	err := i.Save(&b)
+	require.NoError(t, err)
+
+	// Keys saved by the first version are migrated when loaded.
+	saved := b.Bytes()
+	saved[len(snapshotMagic)] = 1
+	l, err := Load(bytes.NewReader(saved), &Options{
+		Tokenizer: new(fieldTokenizer),
+	})
+	require.NoError(t, err)
+	require.Equal(t, stores.Grams{`a\\b c`: {"d": 1}}, storeGrams(t, l.Store))
+}
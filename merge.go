@@ -0,0 +1,244 @@
+package ngrams
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	stores "github.com/mochi-co/ngrams/stores"
+	tk "github.com/mochi-co/ngrams/tokenizers"
+)
+
+var (
+	// ErrIncompatibleIndex indicates that two indexes can't be merged or
+	// compared because they have a different N or tokenizer, record
+	// different orders of ngrams, or only one uses sentence markers.
+	ErrIncompatibleIndex = errors.New("indexes are not compatible")
+
+	// ErrInvalidWeight indicates that a merge weight was not positive.
+	ErrInvalidWeight = errors.New("merge weight must be positive")
+
+	// ErrInvalidRatio indicates that a diff ratio was less than 1.
+	ErrInvalidRatio = errors.New("diff ratio must be at least 1")
+)
+
+// Merge adds every ngram in another index to this index, with each count
+// multiplied by weight and rounded to the nearest whole count, so a weight of
+// 1 sums the counts of both indexes. The indexes must have the same N and
+// tokenizer, record the same orders of ngrams, and both use sentence markers
+// or neither. The other index's store must implement stores.Iterator.
+func (i *Index) Merge(other *Index, weight float64) error {
+	if weight <= 0 || math.IsInf(weight, 0) || math.IsNaN(weight) {
+		return ErrInvalidWeight
+	}
+
+	err := i.compatible(other)
+	if err != nil {
+		return err
+	}
+
+	if i.continuations() != other.continuations() {
+		return ErrIncompatibleIndex
+	}
+
+	// The ngrams are copied before any are added, in case the other index
+	// shares a store with this one.
+	grams, err := other.grams()
+	if err != nil {
+		return err
+	}
+
+	for key, v := range grams {
+		for future, n := range v {
+			c := int64(math.Round(float64(n) * weight))
+			if c <= 0 {
+				continue
+			}
+
+			err = stores.AddN(i.Store, key, future, c)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// NgramDiff is an ngram which is present in only one of two indexes, or is
+// significantly more frequent in one than the other.
+type NgramDiff struct {
+
+	// Ngram contains the tokens of the ngram.
+	Ngram []string
+
+	// Count is the number of times the ngram was indexed in this index.
+	Count int64
+
+	// OtherCount is the number of times the ngram was indexed in the other
+	// index.
+	OtherCount int64
+
+	// Ratio is the frequency of the ngram in this index divided by its
+	// frequency in the other, where the frequency is its count relative to
+	// all ngrams of the same order. It is +Inf if the ngram is only in this
+	// index, and 0 if it is only in the other.
+	Ratio float64
+}
+
+// Diff compares the ngrams of this index with another, returning those which
+// are only in one of them, or whose frequency in one is more than ratio times
+// their frequency in the other. A ratio of 1 returns every ngram whose
+// frequency differs. The ngrams are ordered with the greatest difference
+// first, then the most frequent. The indexes must have the same N and
+// tokenizer, record the same orders of ngrams, and both use sentence markers
+// or neither. Both stores must implement stores.Iterator.
+func (i *Index) Diff(other *Index, ratio float64) ([]NgramDiff, error) {
+	if ratio < 1 || math.IsNaN(ratio) {
+		return nil, ErrInvalidRatio
+	}
+
+	err := i.compatible(other)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := i.grams()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := other.grams()
+	if err != nil {
+		return nil, err
+	}
+
+	at, bt := orderTotals(a), orderTotals(b)
+
+	var diffs []NgramDiff
+	compare := func(key, future string, n, m int64) {
+		tokens := append(stores.SplitKey(key), future)
+		d := NgramDiff{
+			Ngram:      tokens,
+			Count:      n,
+			OtherCount: m,
+		}
+
+		switch {
+		case m == 0:
+			d.Ratio = math.Inf(1)
+		case n == 0:
+			d.Ratio = 0
+		default:
+			order := len(tokens)
+			d.Ratio = (float64(n) / float64(at[order])) / (float64(m) / float64(bt[order]))
+			if d.Ratio <= ratio && 1/d.Ratio <= ratio {
+				return
+			}
+		}
+
+		diffs = append(diffs, d)
+	}
+
+	// Continuation counts are derived from the ngrams, so aren't compared.
+	for key, v := range a {
+		if strings.HasPrefix(key, continuationPrefix) {
+			continue
+		}
+
+		for future, n := range v {
+			compare(key, future, n, b[key][future])
+		}
+	}
+
+	for key, v := range b {
+		if strings.HasPrefix(key, continuationPrefix) {
+			continue
+		}
+
+		for future, m := range v {
+			if a[key][future] == 0 {
+				compare(key, future, 0, m)
+			}
+		}
+	}
+
+	sort.Slice(diffs, func(x, y int) bool {
+		dx, dy := math.Abs(math.Log(diffs[x].Ratio)), math.Abs(math.Log(diffs[y].Ratio))
+		if dx != dy {
+			return dx > dy
+		}
+
+		cx, cy := diffs[x].Count+diffs[x].OtherCount, diffs[y].Count+diffs[y].OtherCount
+		if cx != cy {
+			return cx > cy
+		}
+
+		return stores.JoinKey(diffs[x].Ngram) < stores.JoinKey(diffs[y].Ngram)
+	})
+
+	return diffs, nil
+}
+
+// compatible returns ErrIncompatibleIndex if another index has a different N
+// or tokenizer, records different orders of ngrams, or differs in its use of
+// sentence markers.
+func (i *Index) compatible(other *Index) error {
+	if i.N != other.N || tokenizerID(i.Tokenizer) != tokenizerID(other.Tokenizer) ||
+		i.allOrders() != other.allOrders() || i.SentenceMarkers != other.SentenceMarkers {
+		return ErrIncompatibleIndex
+	}
+
+	return nil
+}
+
+// tokenizerID returns the ID of a tokenizer, or its type if it doesn't
+// implement tokenizers.Identifier.
+func tokenizerID(t tk.Tokenizer) string {
+	if id, ok := t.(tk.Identifier); ok {
+		return id.ID()
+	}
+
+	return fmt.Sprintf("%T", t)
+}
+
+// grams returns a copy of every ngram in the index, so that the store can be
+// changed without iterating it. The store must implement stores.Iterator.
+func (i *Index) grams() (stores.Grams, error) {
+	it, ok := i.Store.(stores.Iterator)
+	if !ok {
+		return nil, ErrNotIterable
+	}
+
+	grams := make(stores.Grams)
+	err := it.Each(func(key string, v stores.Variations) error {
+		c := make(stores.Variations, len(v))
+		for future, n := range v {
+			c[future] = n
+		}
+		grams[key] = c
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return grams, nil
+}
+
+// orderTotals returns the total count of the ngrams of each order, excluding
+// continuation counts.
+func orderTotals(grams stores.Grams) map[int]int64 {
+	totals := make(map[int]int64)
+	for key, v := range grams {
+		if strings.HasPrefix(key, continuationPrefix) {
+			continue
+		}
+
+		totals[len(stores.SplitKey(key))+1] += v.Total()
+	}
+
+	return totals
+}
@@ -0,0 +1,109 @@
+package ngrams
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	stores "github.com/mochi-co/ngrams/stores"
+	tk "github.com/mochi-co/ngrams/tokenizers"
+)
+
+func TestMerge(t *testing.T) {
+	a := NewIndex(2, nil)
+	a.Parse("to be or not to be")
+	b := NewIndex(2, nil)
+	b.Parse("to be or to go")
+
+	err := a.Merge(b, 1)
+	require.NoError(t, err)
+	require.Equal(t, stores.Grams{
+		"to":  {"be": 3, "go": 1},
+		"be":  {"or": 2},
+		"or":  {"not": 1, "to": 1},
+		"not": {"to": 1},
+	}, storeGrams(t, a.Store))
+
+	// Weighted counts are rounded, and dropped if they round to zero.
+	c := NewIndex(2, nil)
+	c.Parse("to be or to go")
+	err = c.Merge(a, 0.4)
+	require.NoError(t, err)
+	require.Equal(t, stores.Grams{
+		"to": {"be": 2, "go": 1},
+		"be": {"or": 2},
+		"or": {"to": 1},
+	}, storeGrams(t, c.Store))
+
+	// An index can be merged into itself.
+	err = b.Merge(b, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), b.Count("to", "go"))
+}
+
+func TestMergeIncompatible(t *testing.T) {
+	a := NewIndex(3, nil)
+
+	err := a.Merge(NewIndex(3, nil), 0)
+	require.Equal(t, ErrInvalidWeight, err)
+	err = a.Merge(NewIndex(3, nil), math.NaN())
+	require.Equal(t, ErrInvalidWeight, err)
+
+	err = a.Merge(NewIndex(2, nil), 1)
+	require.Equal(t, ErrIncompatibleIndex, err)
+
+	err = a.Merge(NewIndex(3, &Options{Tokenizer: tk.NewDefaultWordTokenizer(false)}), 1)
+	require.Equal(t, ErrIncompatibleIndex, err)
+
+	err = a.Merge(NewIndex(3, &Options{Tokenizer: new(fieldTokenizer)}), 1)
+	require.Equal(t, ErrIncompatibleIndex, err)
+
+	err = a.Merge(NewIndex(3, &Options{AllOrders: true}), 1)
+	require.Equal(t, ErrIncompatibleIndex, err)
+
+	err = a.Merge(NewIndex(3, &Options{SentenceMarkers: true}), 1)
+	require.Equal(t, ErrIncompatibleIndex, err)
+
+	err = a.Merge(NewIndex(3, &Options{Store: &plainStore{stores.NewMemoryStore()}}), 1)
+	require.Equal(t, ErrNotIterable, err)
+}
+
+func TestDiff(t *testing.T) {
+	a := NewIndex(2, nil)
+	a.Parse("to be to be to be to go")
+	b := NewIndex(2, nil)
+	b.Parse("to be to go to go to do")
+
+	diffs, err := a.Diff(b, 2)
+	require.NoError(t, err)
+	require.Equal(t, []NgramDiff{
+		{Ngram: []string{"go", "to"}, Count: 0, OtherCount: 2, Ratio: 0},
+		{Ngram: []string{"to", "do"}, Count: 0, OtherCount: 1, Ratio: 0},
+		{Ngram: []string{"be", "to"}, Count: 3, OtherCount: 1, Ratio: 3},
+		{Ngram: []string{"to", "be"}, Count: 3, OtherCount: 1, Ratio: 3},
+	}, diffs)
+
+	// A lower ratio also finds the less frequent ngrams.
+	diffs, err = a.Diff(b, 1)
+	require.NoError(t, err)
+	require.Equal(t, 5, len(diffs))
+	require.Equal(t, []string{"to", "go"}, diffs[4].Ngram)
+	require.Equal(t, 0.5, diffs[4].Ratio)
+
+	diffs, err = a.Diff(a, 1)
+	require.NoError(t, err)
+	require.Empty(t, diffs)
+
+	_, err = a.Diff(b, 0.5)
+	require.Equal(t, ErrInvalidRatio, err)
+
+	for _, other := range []*Index{
+		NewIndex(3, nil),
+		NewIndex(2, &Options{AllOrders: true}),
+		NewIndex(2, &Options{SentenceMarkers: true}),
+	} {
+		_, err = a.Diff(other, 2)
+		require.Equal(t, ErrIncompatibleIndex, err)
+	}
+}
@@ -2,9 +2,10 @@ package ngrams
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"io"
-	"strings"
+	"math/rand"
 
 	stores "github.com/mochi-co/ngrams/stores"
 	tk "github.com/mochi-co/ngrams/tokenizers"
@@ -23,6 +24,10 @@ var (
 	// ErrNoResult indicates that no result could be returned. This shouldn't
 	// really happen outside of tests.
 	ErrNoResult = errors.New("no result found")
+
+	// ErrNoSentenceMarkers indicates that sentence-based generation was
+	// requested from an index which does not use sentence markers.
+	ErrNoSentenceMarkers = errors.New("index does not use sentence markers")
 )
 
 // Options contains parameters for the ngram indexer.
@@ -33,6 +38,34 @@ type Options struct {
 
 	// Tokenizer is the tokenizer to use to split strings into tokens.
 	Tokenizer tk.Tokenizer
+
+	// Smoother is the smoothing or backoff strategy used to estimate the
+	// probability of tokens following contexts that were rarely or never
+	// indexed. Setting a smoother causes the index to record every order of
+	// ngram from unigrams up to N.
+	Smoother Smoother
+
+	// AllOrders indicates that every order of ngram from unigrams up to N
+	// should be recorded, rather than only ngrams of exactly N tokens.
+	AllOrders bool
+
+	// SentenceMarkers indicates that sentence start and end markers should be
+	// indexed around each sentence, so that generated text can begin at the
+	// start of a sentence and stop at the end of one.
+	SentenceMarkers bool
+
+	// Rand is the source of randomness used for generation and store sampling.
+	// Using a source with a fixed seed will always generate the same output
+	// from the same index. If nil, a randomly seeded source is used.
+	Rand *rand.Rand
+
+	// BatchSize is the number of ngrams which Read and Parse buffer
+	// before adding them to the store. If 0, the default of 1024 is used.
+	BatchSize int
+
+	// Workers is the number of documents which ReadAll and LearnFiles read
+	// concurrently. If 0, the number of CPUs is used.
+	Workers int
 }
 
 // Index indexes ngrams and provides meachnisms for ngram retrieval and
@@ -47,6 +80,35 @@ type Index struct {
 
 	// Tokenizer is the tokenizer to use to split strings into tokens.
 	Tokenizer tk.Tokenizer
+
+	// Smoother is the smoothing or backoff strategy used for lookups. If nil,
+	// only exact N-1 token contexts are consulted.
+	Smoother Smoother
+
+	// AllOrders indicates that every order of ngram from unigrams up to N is
+	// recorded. This is always the case if a Smoother is set.
+	AllOrders bool
+
+	// SentenceMarkers indicates that sentence start and end markers are indexed
+	// around each sentence.
+	SentenceMarkers bool
+
+	// Rand is the default source of randomness used for generation and store
+	// sampling. A *rand.Rand is not safe for concurrent use, so when generating
+	// from several goroutines each should provide its own in GenerateOptions.
+	Rand *rand.Rand
+
+	// BatchSize is the number of ngrams which Read and Parse buffer
+	// before adding them to the store.
+	BatchSize int
+
+	// Workers is the number of documents which ReadAll and LearnFiles read
+	// concurrently.
+	Workers int
+
+	// cache contains the totals derived from the store by the smoother, if
+	// the index is a copy made for a single call.
+	cache *smoothingCache
 }
 
 // NewIndex returns a pointer to an Ngrams Index. It can be initialized
@@ -73,6 +135,12 @@ func NewIndex(n int, o *Options) *Index {
 		if o.Tokenizer != nil {
 			i.Tokenizer = o.Tokenizer
 		}
+		i.Smoother = o.Smoother
+		i.AllOrders = o.AllOrders
+		i.SentenceMarkers = o.SentenceMarkers
+		i.Rand = o.Rand
+		i.BatchSize = o.BatchSize
+		i.Workers = o.Workers
 	}
 
 	return i
@@ -90,33 +158,31 @@ func (i *Index) Close() error {
 
 // Read reads from an io.Reader and adds extracted tokens to the store.
 func (i *Index) Read(r io.Reader) (err error) {
+	return i.ReadContext(context.Background(), r)
+}
 
-	// Use the tokenizer scanner to split the read data.
-	scanner := bufio.NewScanner(r)
-	scanner.Split(i.Tokenizer.Scanner)
-
-	// Hold only N tokens at a time.
-	tokens := make([]string, 0, i.N)
+// ReadContext reads from an io.Reader and adds extracted tokens to the store,
+// as per Read. The ngrams are buffered and added to the store in batches of
+// BatchSize, using stores.AddBatch. Reading stops with the context's error if
+// it is cancelled, leaving any tokens which were already read in the store.
+func (i *Index) ReadContext(ctx context.Context, r io.Reader) (err error) {
+	b := i.newBatch()
+	err = i.read(ctx, r, b.add)
 
-	// We need to scan through the first N tokens to ensure there's enough
-	// grams to index (eg trigrams, must have 3 ngrams).
-	for scanner.Scan() {
-		tokens = append(tokens, scanner.Text())
+	return b.finish(ctx, i.Store, err)
+}
 
-		// If we've got enough n-grams, we can start indexing them.
-		if len(tokens) == i.N {
-			err = i.extractAndStore(0, tokens)
-			if err != nil {
-				return
-			}
+// read reads from an io.Reader, storing each ngram of the extracted tokens
+// with the store function.
+func (i *Index) read(ctx context.Context, r io.Reader, store storeFunc) (err error) {
 
-			break
-		}
-	}
+	// Use the tokenizer scanner to split the read data.
+	scanner := bufio.NewScanner(r)
+	scanner.Split(i.Tokenizer.Scanner)
 
+	g := i.newIngester(ctx, store)
 	for scanner.Scan() {
-		tokens = append(tokens[1:], scanner.Text())
-		err = i.extractAndStore(0, tokens)
+		err = g.push(scanner.Text())
 		if err != nil {
 			return
 		}
@@ -127,26 +193,149 @@ func (i *Index) Read(r io.Reader) (err error) {
 		return
 	}
 
+	err = g.close()
+	if err != nil {
+		return
+	}
+
 	return
 }
 
 // Parse parses a string into ngrams and adds them to the index.
 func (i *Index) Parse(str string) (tokens []string, err error) {
+	return i.ParseContext(context.Background(), str)
+}
+
+// ParseContext parses a string into ngrams and adds them to the index, as per
+// Parse. The ngrams are buffered and added to the store in batches, as per
+// ReadContext. Parsing stops with the context's error if it is cancelled,
+// leaving any tokens which were already parsed in the store.
+func (i *Index) ParseContext(ctx context.Context, str string) (tokens []string, err error) {
+	b := i.newBatch()
+	tokens, err = i.parse(ctx, str, b.add)
+	err = b.finish(ctx, i.Store, err)
+
+	return
+}
+
+// parse parses a string into ngrams, storing each with the store function.
+func (i *Index) parse(ctx context.Context, str string, store storeFunc) (tokens []string, err error) {
 
 	// Tokenize the string using whichever tokenizer was selected.
 	tokens = i.Tokenizer.Tokenize(str)
 
-	// Iterate through the tokens creating n-grams of n length.
+	// Iterate through the tokens creating n-grams of n length, each ending
+	// at the current token.
+	g := i.newIngester(ctx, store)
 	for j := 0; j < len(tokens); j++ {
-		err = i.extractAndStore(j, tokens)
+		err = g.push(tokens[j])
 		if err != nil {
 			return
 		}
 	}
 
+	err = g.close()
+	if err != nil {
+		return
+	}
+
 	return
 }
 
+// storeFunc stores a single ngram key and future in a store, such as by
+// adding or removing it.
+type storeFunc func(ctx context.Context, s stores.Store, key, future string) error
+
+// ingester feeds a stream of tokens into the index, holding only the last N
+// tokens at a time and inserting sentence markers if the index uses them.
+type ingester struct {
+
+	// i is the index being ingested into.
+	i *Index
+
+	// ctx is checked for cancellation between tokens, and is passed to the
+	// store with each ngram.
+	ctx context.Context
+
+	// store stores each ngram.
+	store storeFunc
+
+	// window contains the last N tokens that were ingested.
+	window []string
+
+	// marker inserts sentence markers into the stream, if enabled.
+	marker *marker
+}
+
+// newIngester returns an ingester for the index which stores each ngram with
+// the store function.
+func (i *Index) newIngester(ctx context.Context, store storeFunc) *ingester {
+	g := &ingester{
+		i:      i,
+		ctx:    ctx,
+		store:  store,
+		window: make([]string, 0, i.N+1),
+	}
+
+	if i.SentenceMarkers {
+		g.marker = i.newMarker()
+	}
+
+	return g
+}
+
+// push ingests a token, storing each ngram which ends with it.
+func (g *ingester) push(token string) error {
+	err := g.ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	if g.marker == nil {
+		return g.add(token)
+	}
+
+	for _, t := range g.marker.next(token) {
+		err := g.add(t)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// close ends the stream of tokens, closing any unfinished sentence.
+func (g *ingester) close() error {
+	if g.marker == nil {
+		return nil
+	}
+
+	for _, t := range g.marker.close() {
+		err := g.add(t)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// add appends a token to the window and stores the ngrams ending with it.
+// Sentence start markers are only ever context, so are never stored as a future.
+func (g *ingester) add(token string) error {
+	g.window = append(g.window, token)
+	if len(g.window) > g.i.N {
+		g.window = g.window[1:]
+	}
+
+	if token == SentenceStart {
+		return nil
+	}
+
+	return g.i.storeWindow(g.ctx, g.window, g.store)
+}
+
 // extractNgram extracts the maximum possible length ngram from a slice of
 // tokens, starting at index and continuing until either n or len(tokens)
 // has been met.
@@ -162,16 +351,13 @@ func (i *Index) extractNgram(j int, tokens []string) (key, future string) {
 		return
 	}
 
-	// Using whitespace as token joiners. In most cases whitespace is stripped
-	// in the tokenizer, so it will be unique. If you were using this in a
-	// different scenario (say, biological analysis) and you observed
-	// whitespace as a valid character, you could run into problems if there
-	// were competing entries: "a", "b", and "a b".
-	// That's a bit out of scope, but worth noting nonetheless.
+	// The key escapes any whitespace within the tokens, so tokenizers which
+	// keep whitespace (say, for biological analysis) don't see competing
+	// entries collide: "a b" + "c" and "a" + "b c".
 	if n > 0 {
-		key = strings.Join(tokens[j:j+n], " ")
+		key = stores.JoinKey(tokens[j : j+n])
 	} else { // Handle monograms in case anyone wants to do that (n=1, -1, n==0).
-		key = tokens[j]
+		key = stores.JoinKey(tokens[j : j+1])
 	}
 
 	// Only add a future if there's one more token to support it.
@@ -184,15 +370,15 @@ func (i *Index) extractNgram(j int, tokens []string) (key, future string) {
 }
 
 // extractAndStore is a convenience method which extracts ngrams from a slice
-// of tokens, then stores them in the index.
-func (i *Index) extractAndStore(j int, tokens []string) error {
+// of tokens, then stores them in the index with the store function.
+func (i *Index) extractAndStore(ctx context.Context, j int, tokens []string, store storeFunc) error {
 
 	k, f := i.extractNgram(j, tokens)
 	if k == "" {
 		return nil
 	}
 
-	err := i.Store.Add(k, f)
+	err := store(ctx, i.Store, k, f)
 	if err != nil {
 		return err
 	}
@@ -200,11 +386,131 @@ func (i *Index) extractAndStore(j int, tokens []string) error {
 	return nil
 }
 
+// storeWindow stores the ngrams ending at the last token of a window of up
+// to N tokens with the store function. If the index records all orders, every
+// ngram from the unigram up to the full window is stored, otherwise only a
+// full window is.
+func (i *Index) storeWindow(ctx context.Context, window []string, store storeFunc) error {
+	if !i.allOrders() {
+		if len(window) < i.N {
+			return nil
+		}
+
+		return i.extractAndStore(ctx, len(window)-i.N, window, store)
+	}
+
+	for n := 1; n <= len(window); n++ {
+		gram := window[len(window)-n:]
+		err := store(ctx, i.Store, stores.JoinKey(gram[:n-1]), gram[n-1])
+		if err != nil {
+			return err
+		}
+
+		// Kneser-Ney smoothing also needs to know how many distinct tokens
+		// preceded each lower-order ngram, so the preceding token is stored
+		// against the continuation key of the rest of the ngram.
+		if n > 1 && i.continuations() {
+			err = store(ctx, i.Store, continuationKey(gram[1:]), gram[0])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// allOrders returns true if the index should record every order of ngram
+// from unigrams up to N, rather than only ngrams of exactly N tokens.
+// Each order is keyed on its context of n-1 tokens, so the order of any key
+// is one more than the number of tokens in it, and the unigram counts are
+// keyed on the empty context.
+func (i *Index) allOrders() bool {
+	return i.AllOrders || i.Smoother != nil
+}
+
+// continuations returns true if the index should record continuation counts
+// for each lower-order ngram.
+func (i *Index) continuations() bool {
+	_, ok := i.Smoother.(*KneserNey)
+	return ok
+}
+
+// Lookup returns the variations which have been indexed following a context
+// of tokens. Contexts shorter than N-1 tokens are only available if the index
+// records all orders; the empty context returns the unigram counts.
+func (i *Index) Lookup(context []string) (bool, stores.Variations) {
+	return i.Store.Get(stores.JoinKey(context))
+}
+
+// Count returns the number of times an ngram of any order up to N was
+// indexed. Ngrams shorter than N tokens are only counted if the index
+// records all orders.
+func (i *Index) Count(ngram ...string) int64 {
+	if len(ngram) == 0 || len(ngram) > i.N {
+		return 0
+	}
+
+	_, v := i.Lookup(ngram[:len(ngram)-1])
+	return v[ngram[len(ngram)-1]]
+}
+
+// Frequency returns the number of times a single token was indexed. This is
+// only available if the index records all orders.
+func (i *Index) Frequency(token string) int64 {
+	return i.Count(token)
+}
+
+// Vocabulary returns every distinct token that has been indexed, along with
+// the number of times it was indexed. This is only available if the index
+// records all orders.
+func (i *Index) Vocabulary() stores.Variations {
+	_, v := i.Lookup(nil)
+	return v
+}
+
+// Continuations returns the distinct tokens which were indexed immediately
+// preceding an ngram, keyed on the preceding token. This is only recorded
+// when using Kneser-Ney smoothing.
+func (i *Index) Continuations(tokens []string) (bool, stores.Variations) {
+	return i.Store.Get(continuationKey(tokens))
+}
+
+// trimContext returns the last N-1 tokens of a context; anything before that
+// can never have been indexed.
+func (i *Index) trimContext(context []string) []string {
+	n := i.N - 1
+	if len(context) > n {
+		return context[len(context)-n:]
+	}
+
+	return context
+}
+
+// backoff returns the longest suffix of a context which has any indexed
+// variations, along with those variations. If nothing at all has been
+// indexed, the returned variations will be nil.
+func (i *Index) backoff(context []string) ([]string, stores.Variations) {
+	context = i.trimContext(context)
+	for j := 0; j <= len(context); j++ {
+		ok, v := i.Lookup(context[j:])
+		if ok && len(v) > 0 {
+			return context[j:], v
+		}
+	}
+
+	return nil, nil
+}
+
 // Result contains the result of a ngram lookup.
 type Result struct {
 
-	// Prefix is the last token of the key that was matched. It is added
-	// to a key from the variations to make the next key, eg. Prefix+" "+VKey.
+	// Key is the key that was matched.
+	Key string
+
+	// Prefix is the last token of the key that was matched. It is joined
+	// with a key from the variations to make the next key, eg.
+	// stores.JoinKey([]string{Prefix, VKey}).
 	Prefix string
 
 	// Next contains the future variations of the ngram and the number of times
@@ -212,7 +518,8 @@ type Result struct {
 	Next stores.Variations
 }
 
-// Seek returns potential ngrams from the store matching the seed string.
+// Seek returns potential ngrams from the store matching the seed string. If
+// the index records all orders, seeds shorter than N-1 tokens can also be found.
 func (i *Index) Seek(key string) (ok bool, result *Result) {
 	var v stores.Variations
 	ok, v = i.Store.Get(key)
@@ -220,61 +527,18 @@ func (i *Index) Seek(key string) (ok bool, result *Result) {
 		return
 	}
 
-	// Use the tokenizer to split the key, and return the last token as
-	// part of the result to make next lookups more convenient.
-	parts := i.Tokenizer.Tokenize(key)
+	// Split the key into its tokens, and return the last token as part of
+	// the result to make next lookups more convenient.
+	parts := stores.SplitKey(key)
 	if len(parts) == 0 {
 		return
 	}
 
 	result = &Result{
+		Key:    key,
 		Prefix: parts[len(parts)-1],
 		Next:   v,
 	}
 
 	return
 }
-
-// Babble generates a random sequence of up to n ngrams. The future ngrams will be
-// selected based on their probability. The n value total will include discrete
-// punctuation depending on the tokenizer in use.
-func (i *Index) Babble(start string, n int) (b string, err error) {
-
-	// We need the start string as the first tokens in the selected output,
-	// otherwise they'll be skipped. If the string is blank, this will start
-	// with an empty slice and immediately seek any ngram.
-	o := i.Tokenizer.Tokenize(start)
-
-	// For however many tokens we want to use, we'll range through looking
-	// for matching ngram keys.
-	for j := 0; j < n; j++ {
-		ok, r := i.Seek(start)
-		if !ok { // If nothing was found for the ngram, pick a new ngram at random.
-			k, _, err := i.Store.Any()
-			if err != nil {
-				return "", err
-			}
-
-			if k == "" {
-				return "", ErrEmptyIndex
-			}
-
-			ok, r = i.Seek(k)
-		}
-
-		if r == nil {
-			return "", ErrNoResult
-		}
-
-		// Get the next ngram using a weighted random selection from the variations.
-		next := r.Next.NextWeightedRand()
-		start = r.Prefix + " " + next
-		if next != "" {
-			o = append(o, next)
-		}
-	}
-
-	// Output the string using the token formatter for the in-use tokenizer.
-	b = i.Tokenizer.Format(o)
-	return
-}
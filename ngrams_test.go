@@ -1,6 +1,7 @@
 package ngrams
 
 import (
+	"context"
 	"errors"
 	"io/ioutil"
 	"os"
@@ -30,6 +31,22 @@ func (m *MockTokenizer) Format(tokens []string) string {
 	return m.formatted
 }
 
+// fieldTokenizer splits a string into tokens at each "|", so tokens can
+// contain spaces.
+type fieldTokenizer struct{}
+
+func (f *fieldTokenizer) Tokenize(str string) []string {
+	return strings.Split(str, "|")
+}
+
+func (f *fieldTokenizer) Scanner(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	return
+}
+
+func (f *fieldTokenizer) Format(tokens []string) string {
+	return strings.Join(tokens, "|")
+}
+
 type MockStore struct {
 	added    []string
 	errAdd   bool
@@ -164,6 +181,53 @@ func TestRead(t *testing.T) {
 
 }
 
+// cancellingStore cancels a context once a number of ngrams have been added.
+type cancellingStore struct {
+	MockStore
+	cancel func()
+	after  int
+}
+
+func (s *cancellingStore) AddContext(ctx context.Context, key, future string) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	if len(s.added) == s.after-1 {
+		s.cancel()
+	}
+
+	return s.Add(key, future)
+}
+
+func TestParseContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &cancellingStore{cancel: cancel, after: 3}
+	i := NewIndex(3, &Options{
+		Store: s,
+	})
+
+	_, err := i.ParseContext(ctx, "to be or not to be that is the question")
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, []string{"to be or", "be or not", "or not to"}, s.added)
+
+	_, err = NewIndex(3, nil).ParseContext(ctx, "to be or not")
+	require.Equal(t, context.Canceled, err)
+}
+
+func TestReadContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &cancellingStore{cancel: cancel, after: 2}
+	i := NewIndex(3, &Options{
+		Store: s,
+	})
+
+	err := i.ReadContext(ctx, strings.NewReader("to be or not to be that is the question"))
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, []string{"to be or", "be or not"}, s.added)
+}
+
 func TestClose(t *testing.T) {
 	i := NewIndex(3, nil)
 	err := i.Close()
@@ -231,7 +295,7 @@ func TestExtractAndStore(t *testing.T) {
 		Store: new(MockStore),
 	})
 	for j := 0; j < len(tokens); j++ {
-		err := i.extractAndStore(j, tokens)
+		err := i.extractAndStore(context.Background(), j, tokens, stores.AddContext)
 		require.NoError(t, err)
 	}
 
@@ -288,7 +352,20 @@ func TestSeek(t *testing.T) {
 	})
 	ok, result = i.Seek("to be or")
 	require.Equal(t, true, ok)
-	require.Nil(t, result)
+	require.Equal(t, "or", result.Prefix) // keys are split by the store encoding
+	require.Equal(t, stores.Variations{"test": 100}, result.Next)
+
+	// Tokens containing spaces don't collide with their neighbours.
+	i = NewIndex(3, &Options{
+		Tokenizer: new(fieldTokenizer),
+	})
+	i.Parse("a b|c|d|a|b c|e")
+	ok, result = i.Seek(stores.JoinKey([]string{"a b", "c"}))
+	require.Equal(t, true, ok)
+	require.Equal(t, "c", result.Prefix)
+	require.Equal(t, stores.Variations{"d": 1}, result.Next)
+	require.Equal(t, int64(1), i.Count("a", "b c", "e"))
+	require.Equal(t, int64(0), i.Count("a b", "c", "e"))
 
 }
 
@@ -351,3 +428,81 @@ func TestShortBabble(t *testing.T) {
 	require.Error(t, err)
 	require.Equal(t, ErrNoResult, err)
 }
+
+func TestAllOrders(t *testing.T) {
+	i := NewIndex(3, &Options{
+		AllOrders: true,
+		Store:     new(MockStore),
+	})
+	require.True(t, i.AllOrders)
+	require.Nil(t, i.Smoother)
+
+	_, err := i.Parse("to be or not")
+	require.NoError(t, err)
+	ex := []string{
+		" to",
+		" be",
+		"to be",
+		" or",
+		"be or",
+		"to be or",
+		" not",
+		"or not",
+		"be or not",
+	}
+	require.Equal(t, ex, i.Store.(*MockStore).added)
+
+	i.Store = new(MockStore)
+	err = i.Read(strings.NewReader("to be or not"))
+	require.NoError(t, err)
+	require.Equal(t, ex, i.Store.(*MockStore).added)
+
+	i.Store = &MockStore{
+		errAdd: true,
+	}
+	_, err = i.Parse("to be or not")
+	require.Error(t, err)
+
+	// Shorter keys can be seeked when all orders are recorded.
+	i = NewIndex(3, &Options{
+		AllOrders: true,
+	})
+	i.Parse("to be or not to be that is the question")
+	ok, result := i.Seek("be")
+	require.True(t, ok)
+	require.Equal(t, "be", result.Prefix)
+	require.Equal(t, stores.Variations{"or": 1, "that": 1}, result.Next)
+
+	b, err := i.Babble("unseen", 5)
+	require.NoError(t, err)
+	require.NotEmpty(t, b)
+}
+
+func TestCount(t *testing.T) {
+	i := NewIndex(3, &Options{
+		AllOrders: true,
+	})
+	i.Parse("to be or not to be that is the question")
+
+	require.Equal(t, int64(2), i.Count("to", "be"))
+	require.Equal(t, int64(1), i.Count("to", "be", "or"))
+	require.Equal(t, int64(0), i.Count("to", "be", "question"))
+	require.Equal(t, int64(0), i.Count("to", "be", "or", "not"))
+	require.Equal(t, int64(0), i.Count())
+
+	require.Equal(t, int64(2), i.Frequency("be"))
+	require.Equal(t, int64(1), i.Frequency("question"))
+	require.Equal(t, int64(0), i.Frequency("unseen"))
+
+	v := i.Vocabulary()
+	require.Equal(t, 8, len(v))
+	require.Equal(t, int64(10), v.Total())
+
+	// Only the top order is available without all orders.
+	i = NewIndex(3, nil)
+	i.Parse("to be or not to be that is the question")
+	require.Equal(t, int64(1), i.Count("to", "be", "or"))
+	require.Equal(t, int64(0), i.Count("to", "be"))
+	require.Equal(t, int64(0), i.Frequency("be"))
+	require.Empty(t, i.Vocabulary())
+}
@@ -0,0 +1,162 @@
+package ngrams
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+// Prediction is a possible next token and its probability.
+type Prediction struct {
+
+	// Token is the predicted token.
+	Token string
+
+	// Probability is the conditional probability of the token following the
+	// prefix, P(token|context).
+	Probability float64
+}
+
+// Predict returns the k most likely tokens to follow a prefix, sorted from most
+// to least probable (or every possible token if k is 0). The prefix is tokenized
+// with the index's tokenizer and the last N-1 tokens are used as the context.
+// If the prefix ends mid-word, the last token is first treated as partial and
+// only tokens which complete it are predicted; if there are none, or it could
+// already follow its context as a whole token, it's treated as a whole word and
+// the tokens which follow it are predicted instead. Contexts which were never
+// indexed back off to shorter ones. If the index doesn't record all orders,
+// the shorter contexts are matched against the end of each key, which needs a
+// store implementing stores.PrefixScanner, as do contexts whose case differs
+// from the indexed tokens.
+func (i *Index) Predict(prefix string, k int) ([]Prediction, error) {
+	i = i.cached()
+	tokens := i.Tokenizer.Tokenize(prefix)
+
+	if len(tokens) > 0 && endsMidWord(prefix) {
+		preds, err := i.predict(tokens[:len(tokens)-1], tokens[len(tokens)-1], k)
+		if err != ErrNoResult {
+			return preds, err
+		}
+	}
+
+	return i.predict(tokens, "", k)
+}
+
+// predict returns the k most likely tokens to follow the last N-1 tokens, which
+// begin with partial.
+func (i *Index) predict(tokens []string, partial string, k int) ([]Prediction, error) {
+	context := i.trimContext(tokens)
+	if len(tokens) == 0 && i.SentenceMarkers {
+		context = i.sentenceStart()
+	}
+
+	for c := context; ; c = c[1:] {
+		v := i.predictVariations(c)
+
+		// A partial token which could already follow the context is a whole
+		// word, so the tokens after it are predicted instead.
+		if partial != "" && v[partial] > 0 {
+			return nil, ErrNoResult
+		}
+
+		total := float64(v.Total())
+
+		var preds []Prediction
+		for token, n := range v {
+			if token == SentenceEnd || !strings.HasPrefix(token, partial) {
+				continue
+			}
+
+			p := float64(n) / total
+			if i.Smoother != nil {
+				p = i.Smoother.Probability(i, context, token)
+			}
+
+			preds = append(preds, Prediction{
+				Token:       token,
+				Probability: p,
+			})
+		}
+
+		if len(preds) > 0 {
+			sortPredictions(preds)
+			if k > 0 && len(preds) > k {
+				preds = preds[:k]
+			}
+
+			return preds, nil
+		}
+
+		if len(c) == 0 {
+			break
+		}
+	}
+
+	return nil, ErrNoResult
+}
+
+// predictVariations returns the variations which followed a context. If the
+// context was never indexed as it is, because it's shorter than the keys of an
+// index which doesn't record all orders or its case differs, the variations of
+// every key of the same order which ends with the context in any case are
+// summed, so long as the store implements stores.PrefixScanner.
+func (i *Index) predictVariations(context []string) stores.Variations {
+	if len(context) == i.N-1 || i.allOrders() {
+		_, v := i.Lookup(context)
+		if len(v) > 0 {
+			return v
+		}
+	}
+
+	s, ok := i.Store.(stores.PrefixScanner)
+	if !ok {
+		return nil
+	}
+
+	n := i.N - 1
+	if i.allOrders() {
+		n = len(context)
+	}
+
+	v := make(stores.Variations)
+	s.ScanPrefix("", func(key string, kv stores.Variations) error {
+		if strings.HasPrefix(key, continuationPrefix) {
+			return nil
+		}
+
+		tokens := stores.SplitKey(key)
+		if len(tokens) != n || !hasSuffixFold(tokens, context) {
+			return nil
+		}
+
+		for future, c := range kv {
+			v[future] += c
+		}
+
+		return nil
+	})
+
+	return v
+}
+
+// endsMidWord returns true if a string ends with a letter or number, rather
+// than whitespace or punctuation.
+func endsMidWord(str string) bool {
+	r, _ := utf8.DecodeLastRuneInString(str)
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+// sortPredictions sorts predictions from most to least probable. Predictions
+// with the same probability are sorted by token.
+func sortPredictions(preds []Prediction) {
+	sort.Slice(preds, func(a, b int) bool {
+		if preds[a].Probability != preds[b].Probability {
+			return preds[a].Probability > preds[b].Probability
+		}
+
+		return preds[a].Token < preds[b].Token
+	})
+}
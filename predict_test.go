@@ -0,0 +1,139 @@
+package ngrams
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+const predictText = "to be or not to be or not to be or to be, that is the question. to beat the bell."
+
+func TestPredict(t *testing.T) {
+	i := NewIndex(3, nil)
+	i.Parse(predictText)
+
+	preds, err := i.Predict("to be ", 0)
+	require.NoError(t, err)
+	ex := []Prediction{
+		{"or", 0.75},
+		{",", 0.25},
+	}
+	require.Equal(t, ex, preds)
+
+	// Only the last N-1 tokens are used.
+	preds, err = i.Predict("this is the question. to ", 1)
+	require.NoError(t, err)
+	require.Equal(t, []Prediction{{"beat", 1}}, preds)
+
+	// The prefix ends mid-word, so only completions are predicted.
+	preds, err = i.Predict("not to b", 0)
+	require.NoError(t, err)
+	require.Equal(t, []Prediction{{"be", 1}}, preds)
+
+	preds, err = i.Predict("that is the q", 0)
+	require.NoError(t, err)
+	require.Equal(t, []Prediction{{"question", 1}}, preds)
+
+	// A prefix ending in a whole word which completes nothing predicts the
+	// token after it.
+	preds, err = i.Predict("to be", 0)
+	require.NoError(t, err)
+	require.Equal(t, ex, preds)
+
+	// A partial word which could already follow its context is treated as a
+	// whole word, even if it could also be completed.
+	preds, err = i.Predict("not to be", 0)
+	require.NoError(t, err)
+	require.Equal(t, ex, preds)
+
+	// Without all orders, short prefixes back off to the keys which end with
+	// them, in any case.
+	preds, err = i.Predict("to ", 0)
+	require.NoError(t, err)
+	require.Equal(t, []Prediction{{"be", 0.75}, {"beat", 0.25}}, preds)
+
+	preds, err = i.Predict("Not To ", 0)
+	require.NoError(t, err)
+	require.Equal(t, []Prediction{{"be", 1}}, preds)
+
+	preds, err = i.Predict("be", 0)
+	require.NoError(t, err)
+	require.Equal(t, ex, preds)
+
+	// Keys can only be matched by their ends if the store can scan them.
+	i = NewIndex(3, &Options{
+		Store: &plainStore{stores.NewMemoryStore()},
+	})
+	i.Parse(predictText)
+
+	_, err = i.Predict("to ", 0)
+	require.Equal(t, ErrNoResult, err)
+
+	_, err = i.Predict("that is the x", 0)
+	require.Equal(t, ErrNoResult, err)
+}
+
+func TestPredictBackoff(t *testing.T) {
+	i := NewIndex(3, &Options{
+		AllOrders: true,
+	})
+	i.Parse(predictText)
+
+	preds, err := i.Predict("to ", 0)
+	require.NoError(t, err)
+	require.Equal(t, []Prediction{{"be", 0.8}, {"beat", 0.2}}, preds)
+
+	preds, err = i.Predict("something unseen the ", 0)
+	require.NoError(t, err)
+	require.Equal(t, []Prediction{{"bell", 0.5}, {"question", 0.5}}, preds)
+
+	// Partial words back off until a completion is found.
+	preds, err = i.Predict("the cat b", 0)
+	require.NoError(t, err)
+	require.Equal(t, []Prediction{{"be", 4.0 / 24.0}, {"beat", 1.0 / 24.0}, {"bell", 1.0 / 24.0}}, preds)
+
+	// A partial word which completes nothing is treated as a whole word.
+	preds, err = i.Predict("the cat xyz", 0)
+	require.NoError(t, err)
+	ex, err := i.Predict("the cat xyz ", 0)
+	require.NoError(t, err)
+	require.Equal(t, ex, preds)
+
+	// Smoothed probabilities are used if a smoother is set.
+	i = NewIndex(3, &Options{
+		Smoother: NewKneserNey(0),
+	})
+	i.Parse(predictText)
+	preds, err = i.Predict("to be ", 0)
+	require.NoError(t, err)
+	require.Equal(t, "or", preds[0].Token)
+	require.Equal(t, i.Smoother.Probability(i, []string{"to", "be"}, "or"), preds[0].Probability)
+}
+
+func TestPredictSentenceMarkers(t *testing.T) {
+	i := NewIndex(3, &Options{
+		SentenceMarkers: true,
+	})
+	i.Parse(sentencesText)
+
+	preds, err := i.Predict("", 0)
+	require.NoError(t, err)
+	require.Equal(t, []Prediction{{"the", 2.0 / 3.0}, {"where", 1.0 / 3.0}}, preds)
+
+	// End of sentence markers are never predicted.
+	preds, err = i.Predict("the cat sat on the mat. ", 0)
+	require.NoError(t, err)
+	for _, p := range preds {
+		require.NotEqual(t, SentenceEnd, p.Token)
+	}
+}
+
+func TestEndsMidWord(t *testing.T) {
+	require.True(t, endsMidWord("to b"))
+	require.True(t, endsMidWord("year 19"))
+	require.False(t, endsMidWord("to be "))
+	require.False(t, endsMidWord("to be,"))
+	require.False(t, endsMidWord(""))
+}
@@ -0,0 +1,234 @@
+package ngrams
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+const (
+
+	// Unknown is the token which rare tokens are replaced with when pruning
+	// with a minimum token count.
+	Unknown = "<unk>"
+)
+
+var (
+	// ErrInvalidPrune indicates that the prune options were out of range.
+	ErrInvalidPrune = errors.New("invalid prune options")
+)
+
+// PruneOptions controls which ngrams are removed when pruning an index.
+type PruneOptions struct {
+
+	// MinCount removes variations which were indexed fewer than MinCount
+	// times. 0 keeps every variation.
+	MinCount int64
+
+	// TopK keeps only the K most frequent variations of each key. 0 keeps
+	// every variation.
+	TopK int
+
+	// MinTokenCount replaces every token which was indexed fewer than
+	// MinTokenCount times with the Unknown token, in both keys and
+	// variations, merging the counts of any ngrams which become the same.
+	// 0 keeps every token.
+	MinTokenCount int64
+}
+
+// PruneResult reports what was removed from an index by pruning.
+type PruneResult struct {
+
+	// Variations is the number of variations which were removed, including
+	// those merged into others.
+	Variations int
+
+	// Keys is the number of keys which were removed, including those merged
+	// into others.
+	Keys int
+
+	// Tokens is the number of distinct tokens replaced with Unknown.
+	Tokens int
+}
+
+// Prune shrinks the index by removing rare ngrams, as set by the options, and
+// reports how many entries were removed. The unigram counts of an index which
+// records all orders are the vocabulary, so are only affected by
+// MinTokenCount, and the continuation counts of Kneser-Ney smoothing are only
+// affected by MinTokenCount too. The store must implement stores.Iterator.
+// Unknown tokens may be generated, so they can be excluded with
+// Constraints.Banned.
+func (i *Index) Prune(o PruneOptions) (*PruneResult, error) {
+	if o.MinCount < 0 || o.TopK < 0 || o.MinTokenCount < 0 {
+		return nil, ErrInvalidPrune
+	}
+
+	// The store can't be changed while it's being iterated, so every ngram is
+	// copied before any are changed.
+	grams, err := i.grams()
+	if err != nil {
+		return nil, err
+	}
+
+	res := new(PruneResult)
+	rare := i.rareTokens(grams, o.MinTokenCount)
+	res.Tokens = len(rare)
+
+	pruned := make(stores.Grams, len(grams))
+	for key, v := range grams {
+		k := pruneKey(key, rare)
+		if pruned[k] == nil {
+			pruned[k] = make(stores.Variations, len(v))
+		}
+
+		for future, n := range v {
+			if rare[future] {
+				future = Unknown
+			}
+			pruned[k][future] += n
+		}
+	}
+
+	for key, v := range pruned {
+		if key == "" || strings.HasPrefix(key, continuationPrefix) {
+			continue
+		}
+
+		pruneVariations(v, o.MinCount, o.TopK)
+		if len(v) == 0 {
+			delete(pruned, key)
+		}
+	}
+
+	// Only the keys which changed are written back to the store.
+	for key, v := range grams {
+		res.Variations += len(v)
+		if !equalVariations(v, pruned[key]) {
+			err = i.Store.Delete(key)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for key, v := range pruned {
+		res.Variations -= len(v)
+		if equalVariations(v, grams[key]) {
+			continue
+		}
+
+		for future, n := range v {
+			err = stores.AddN(i.Store, key, future, n)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	res.Keys = len(grams) - len(pruned)
+
+	return res, nil
+}
+
+// rareTokens returns the tokens which were indexed fewer than min times. If the
+// index records all orders the unigram counts are used, otherwise each token
+// is counted every time it was indexed as a future. Sentence markers are never
+// rare.
+func (i *Index) rareTokens(grams stores.Grams, min int64) map[string]bool {
+	rare := make(map[string]bool)
+	if min <= 1 {
+		return rare
+	}
+
+	counts := grams[""]
+	if !i.allOrders() {
+		counts = make(stores.Variations)
+		for key, v := range grams {
+			if strings.HasPrefix(key, continuationPrefix) {
+				continue
+			}
+
+			for future, n := range v {
+				counts[future] += n
+			}
+		}
+	}
+
+	for token, n := range counts {
+		if n < min && token != SentenceStart && token != SentenceEnd && token != Unknown {
+			rare[token] = true
+		}
+	}
+
+	return rare
+}
+
+// pruneKey returns a key with any rare tokens replaced with Unknown.
+func pruneKey(key string, rare map[string]bool) string {
+	if len(rare) == 0 {
+		return key
+	}
+
+	var prefix string
+	if strings.HasPrefix(key, continuationPrefix) {
+		prefix, key = continuationPrefix, key[len(continuationPrefix):]
+	}
+
+	tokens := stores.SplitKey(key)
+	for j, t := range tokens {
+		if rare[t] {
+			tokens[j] = Unknown
+		}
+	}
+
+	return prefix + stores.JoinKey(tokens)
+}
+
+// pruneVariations removes the variations indexed fewer than min times, then
+// all but the k most frequent. Variations with equal counts are kept in
+// alphabetical order.
+func pruneVariations(v stores.Variations, min int64, k int) {
+	for future, n := range v {
+		if n < min {
+			delete(v, future)
+		}
+	}
+
+	if k == 0 || len(v) <= k {
+		return
+	}
+
+	futures := make([]string, 0, len(v))
+	for future := range v {
+		futures = append(futures, future)
+	}
+
+	sort.Slice(futures, func(a, b int) bool {
+		if v[futures[a]] != v[futures[b]] {
+			return v[futures[a]] > v[futures[b]]
+		}
+
+		return futures[a] < futures[b]
+	})
+
+	for _, future := range futures[k:] {
+		delete(v, future)
+	}
+}
+
+// equalVariations returns true if two sets of variations are the same.
+func equalVariations(a, b stores.Variations) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for future, n := range a {
+		if m, ok := b[future]; !ok || m != n {
+			return false
+		}
+	}
+
+	return true
+}
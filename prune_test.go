@@ -0,0 +1,82 @@
+package ngrams
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+func TestPrune(t *testing.T) {
+	i := NewIndex(2, nil)
+	i.Parse("to be or not to be or to go")
+	require.Equal(t, stores.Grams{
+		"to":  {"be": 2, "go": 1},
+		"be":  {"or": 2},
+		"or":  {"not": 1, "to": 1},
+		"not": {"to": 1},
+	}, storeGrams(t, i.Store))
+
+	res, err := i.Prune(PruneOptions{MinCount: 2})
+	require.NoError(t, err)
+	require.Equal(t, &PruneResult{Variations: 4, Keys: 2}, res)
+	require.Equal(t, stores.Grams{
+		"to": {"be": 2},
+		"be": {"or": 2},
+	}, storeGrams(t, i.Store))
+
+	// Pruning again changes nothing.
+	res, err = i.Prune(PruneOptions{MinCount: 2})
+	require.NoError(t, err)
+	require.Equal(t, &PruneResult{}, res)
+}
+
+func TestPruneTopK(t *testing.T) {
+	i := NewIndex(2, nil)
+	i.Parse("a b a c a c a d a d a d")
+
+	res, err := i.Prune(PruneOptions{TopK: 2})
+	require.NoError(t, err)
+	require.Equal(t, 1, res.Variations)
+	require.Equal(t, 0, res.Keys)
+
+	_, v := i.Lookup([]string{"a"})
+	require.Equal(t, stores.Variations{"d": 3, "c": 2}, v)
+
+	// Ties are broken alphabetically.
+	res, err = i.Prune(PruneOptions{TopK: 1})
+	require.NoError(t, err)
+	_, v = i.Lookup([]string{"b"})
+	require.Equal(t, stores.Variations{"a": 1}, v)
+	_, v = i.Lookup([]string{"c"})
+	require.Equal(t, stores.Variations{"a": 2}, v)
+}
+
+func TestPruneUnknown(t *testing.T) {
+	i := NewIndex(3, &Options{
+		AllOrders: true,
+	})
+	i.Parse("to be or not to be or to go")
+
+	res, err := i.Prune(PruneOptions{MinTokenCount: 2})
+	require.NoError(t, err)
+	require.Equal(t, 2, res.Tokens)
+
+	// "not" and "go" are merged into the unknown token.
+	require.Equal(t, stores.Variations{"to": 3, "be": 2, "or": 2, Unknown: 2}, i.Vocabulary())
+	require.Equal(t, int64(1), i.Count("or", Unknown, "to"))
+	require.Equal(t, int64(1), i.Count("or", "to", Unknown))
+	require.Equal(t, int64(1), i.Count("or", Unknown))
+	require.Equal(t, int64(1), i.Count("to", Unknown))
+	require.Equal(t, int64(0), i.Count("or", "not"))
+
+	_, err = i.Prune(PruneOptions{MinCount: -1})
+	require.Equal(t, ErrInvalidPrune, err)
+
+	i = NewIndex(3, &Options{
+		Store: &plainStore{stores.NewMemoryStore()},
+	})
+	_, err = i.Prune(PruneOptions{MinCount: 2})
+	require.Equal(t, ErrNotIterable, err)
+}
@@ -0,0 +1,179 @@
+package ngrams
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+// ReadAll reads several documents concurrently and adds their extracted tokens
+// to the store, as though each were read in turn with Read. The documents are
+// tokenized by up to Workers goroutines, each of which counts the ngrams it
+// extracts locally, and the counts are merged into the store in batches once
+// every document has been read, so the final counts are always the same as
+// reading the documents one after another. If any document fails to be read,
+// or the context is cancelled before every document has been read, nothing is
+// added to the store and the first error is returned. Once the counts are
+// being added they are all added, even if the context is cancelled, so only an
+// error from the store itself can leave some of them out.
+func (i *Index) ReadAll(ctx context.Context, readers ...io.Reader) error {
+	return i.readAll(ctx, len(readers), func(j int) (io.ReadCloser, error) {
+		return ioutil.NopCloser(readers[j]), nil
+	})
+}
+
+// LearnFiles reads the files at each path concurrently and adds their extracted
+// tokens to the store, as per ReadAll. Each file is only opened when a worker
+// is ready to read it.
+func (i *Index) LearnFiles(paths ...string) error {
+	return i.LearnFilesContext(context.Background(), paths...)
+}
+
+// LearnFilesContext reads the files at each path concurrently and adds their
+// extracted tokens to the store, as per LearnFiles. Reading stops with the
+// context's error if it is cancelled before every file has been read, and
+// nothing is added to the store.
+func (i *Index) LearnFilesContext(ctx context.Context, paths ...string) error {
+	return i.readAll(ctx, len(paths), func(j int) (io.ReadCloser, error) {
+		return os.Open(paths[j])
+	})
+}
+
+// readAll reads n documents, opened by open, with a pool of workers, then adds
+// the sum of their counts to the store.
+func (i *Index) readAll(ctx context.Context, n int, open func(j int) (io.ReadCloser, error)) error {
+	if n == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := i.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	if workers > n {
+		workers = n
+	}
+
+	jobs := make(chan int)
+	counts := make([]stores.Grams, workers)
+	errs := make([]error, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			grams := make(stores.Grams)
+			for j := range jobs {
+				err := i.readCounted(ctx, open, j, grams)
+				if err != nil {
+					errs[w] = err
+					cancel()
+					return
+				}
+			}
+
+			counts[w] = grams
+		}(w)
+	}
+
+	// Documents are handed out in order until one of the workers fails.
+feed:
+	for j := 0; j < n; j++ {
+		select {
+		case jobs <- j:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	// A worker which failed may have cancelled the others, so its error is
+	// returned in preference to theirs.
+	for _, err := range errs {
+		if err != nil && err != context.Canceled {
+			return err
+		}
+	}
+
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	return i.mergeCounts(counts)
+}
+
+// readCounted reads a single document, adding the counts of its ngrams to a
+// set of grams.
+func (i *Index) readCounted(ctx context.Context, open func(j int) (io.ReadCloser, error), j int, grams stores.Grams) error {
+	r, err := open(j)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return i.read(ctx, r, func(ctx context.Context, s stores.Store, key, future string) error {
+		v, ok := grams[key]
+		if !ok {
+			v = make(stores.Variations)
+			grams[key] = v
+		}
+		v[future]++
+
+		return nil
+	})
+}
+
+// mergeCounts sums the counts of each worker and adds them to the store in
+// batches, ordered by key and future so the store always sees the same
+// sequence. The context isn't checked between batches, so that cancelling it
+// can't leave only some of the counts in the store.
+func (i *Index) mergeCounts(counts []stores.Grams) error {
+	total := counts[0]
+	for _, grams := range counts[1:] {
+		for key, v := range grams {
+			if total[key] == nil {
+				total[key] = v
+				continue
+			}
+
+			for future, c := range v {
+				total[key][future] += c
+			}
+		}
+	}
+
+	entries := make([]stores.Entry, 0, len(total))
+	for key, v := range total {
+		for future, c := range v {
+			entries = append(entries, stores.Entry{
+				Key:    key,
+				Future: future,
+				Count:  c,
+			})
+		}
+	}
+
+	sort.Slice(entries, func(a, b int) bool {
+		if entries[a].Key != entries[b].Key {
+			return entries[a].Key < entries[b].Key
+		}
+
+		return entries[a].Future < entries[b].Future
+	})
+
+	return i.addEntries(context.Background(), entries)
+}
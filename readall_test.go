@@ -0,0 +1,101 @@
+package ngrams
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+func TestReadAll(t *testing.T) {
+	docs := []string{sentencesText, smoothingText, "to be or not to be", "", "that is the question"}
+	for _, o := range []Options{
+		{},
+		{SentenceMarkers: true},
+		{Smoother: NewKneserNey(0.75)},
+		{Workers: 1},
+		{Workers: 3, BatchSize: 2},
+	} {
+		sequential := NewIndex(3, &o)
+		for _, d := range docs {
+			require.NoError(t, sequential.Read(strings.NewReader(d)))
+		}
+
+		o.Store = stores.NewCompactStore()
+		i := NewIndex(3, &o)
+		readers := make([]io.Reader, len(docs))
+		for j, d := range docs {
+			readers[j] = strings.NewReader(d)
+		}
+
+		err := i.ReadAll(context.Background(), readers...)
+		require.NoError(t, err)
+		require.Equal(t, storeGrams(t, sequential.Store), storeGrams(t, i.Store))
+	}
+
+	require.NoError(t, NewIndex(3, nil).ReadAll(context.Background()))
+}
+
+func TestLearnFiles(t *testing.T) {
+	paths := []string{"training/hemingway.txt", "training/pride-prejudice.txt"}
+
+	sequential := NewIndex(3, nil)
+	for _, p := range paths {
+		f, err := os.Open(p)
+		require.NoError(t, err)
+		require.NoError(t, sequential.Read(f))
+		f.Close()
+	}
+
+	i := NewIndex(3, &Options{Workers: 2})
+	require.NoError(t, i.LearnFiles(paths...))
+	require.Equal(t, storeGrams(t, sequential.Store), storeGrams(t, i.Store))
+
+	// Nothing is added if any file can't be read.
+	i = NewIndex(3, nil)
+	err := i.LearnFiles(paths[0], "training/missing.txt")
+	require.True(t, os.IsNotExist(err))
+	require.Empty(t, storeGrams(t, i.Store))
+}
+
+// errReader fails every read.
+type errReader struct{}
+
+func (errReader) Read(p []byte) (int, error) {
+	return 0, errors.New("test")
+}
+
+func TestReadAllErrors(t *testing.T) {
+	i := NewIndex(3, &Options{Workers: 2})
+	err := i.ReadAll(context.Background(), strings.NewReader("to be or not"), errReader{})
+	require.EqualError(t, err, "test")
+	require.Empty(t, storeGrams(t, i.Store))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = i.ReadAll(ctx, strings.NewReader("to be or not"))
+	require.Equal(t, context.Canceled, err)
+	require.Empty(t, storeGrams(t, i.Store))
+
+	// Cancelling the context once the counts are being added to the store
+	// still adds all of them.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	s := &cancellingStore{cancel: cancel, after: 1}
+	i = NewIndex(3, &Options{Store: s, BatchSize: 1})
+	err = i.ReadAll(ctx, strings.NewReader("to be or not"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"be or not", "to be or"}, s.added)
+
+	// Errors adding to the store are returned.
+	i = NewIndex(3, &Options{Store: &MockStore{errAdd: true}})
+	err = i.ReadAll(context.Background(), strings.NewReader("to be or not"))
+	require.Error(t, err)
+}
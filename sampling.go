@@ -0,0 +1,137 @@
+package ngrams
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+var (
+	// ErrInvalidSampling indicates that the sampling controls in the generate
+	// options were out of range.
+	ErrInvalidSampling = errors.New("invalid sampling options")
+)
+
+// candidate is a possible next token and its weight for sampling.
+type candidate struct {
+
+	// token is the candidate token.
+	token string
+
+	// weight is the relative weight of the candidate. Weights do not need to
+	// sum to 1.
+	weight float64
+}
+
+// candidates returns the variations as a slice of candidates weighted by the
+// number of times they were indexed. The candidates are sorted by weight from
+// highest to lowest, and then by token, so they are always in the same order.
+func candidates(v stores.Variations) []candidate {
+	c := make([]candidate, 0, len(v))
+	for token, n := range v {
+		c = append(c, candidate{
+			token:  token,
+			weight: float64(n),
+		})
+	}
+
+	sortCandidates(c)
+	return c
+}
+
+// sortCandidates sorts candidates by weight from highest to lowest, and then
+// by token.
+func sortCandidates(c []candidate) {
+	sort.Slice(c, func(a, b int) bool {
+		if c[a].weight != c[b].weight {
+			return c[a].weight > c[b].weight
+		}
+
+		return c[a].token < c[b].token
+	})
+}
+
+// validate returns an error if any of the sampling controls are out of range.
+func (o *GenerateOptions) validate() error {
+	if o.Temperature < 0 || o.TopK < 0 || o.TopP < 0 || o.TopP > 1 ||
+		o.Restart < RestartUniform || o.Restart > RestartSentenceStart {
+		return ErrInvalidSampling
+	}
+
+	return nil
+}
+
+// sampling returns true if any of the sampling controls have been set, in
+// which case tokens can't be selected by a plain weighted random selection.
+func (o *GenerateOptions) sampling() bool {
+	return o.Greedy || o.TopK > 0 || (o.TopP > 0 && o.TopP < 1) ||
+		(o.Temperature > 0 && o.Temperature != 1)
+}
+
+// sample selects a token from sorted candidates using the sampling controls.
+func (o *GenerateOptions) sample(r *rand.Rand, c []candidate) string {
+	if len(c) == 0 {
+		return ""
+	}
+
+	// Greedy decoding always selects the most likely candidate. As the
+	// candidates are sorted, ties are broken alphabetically.
+	if o.Greedy {
+		return c[0].token
+	}
+
+	// Top-k truncation keeps only the k most likely candidates.
+	if o.TopK > 0 && o.TopK < len(c) {
+		c = c[:o.TopK]
+	}
+
+	// Temperature scaling sharpens (< 1) or flattens (> 1) the distribution.
+	// The weights are scaled relative to the largest so they can't overflow.
+	if o.Temperature > 0 && o.Temperature != 1 {
+		scaled := make([]candidate, len(c))
+		max := math.Log(c[0].weight)
+		for j := range c {
+			scaled[j] = candidate{
+				token:  c[j].token,
+				weight: math.Exp((math.Log(c[j].weight) - max) / o.Temperature),
+			}
+		}
+		c = scaled
+	}
+
+	var total float64
+	for _, cd := range c {
+		total += cd.weight
+	}
+
+	// Nucleus (top-p) sampling keeps the smallest set of the most likely
+	// candidates whose probabilities sum to at least p.
+	if o.TopP > 0 && o.TopP < 1 {
+		var cum float64
+		for j := range c {
+			cum += c[j].weight / total
+			if cum >= o.TopP {
+				c = c[:j+1]
+				break
+			}
+		}
+
+		total = 0
+		for _, cd := range c {
+			total += cd.weight
+		}
+	}
+
+	n := r.Float64() * total
+	for _, cd := range c {
+		n -= cd.weight
+		if n < 0 {
+			return cd.token
+		}
+	}
+
+	return c[len(c)-1].token
+}
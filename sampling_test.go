@@ -0,0 +1,118 @@
+package ngrams
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+var samplingVariations = stores.Variations{
+	"or":  2,
+	"to":  3,
+	"be":  5,
+	"not": 2,
+}
+
+// sampleCounts returns the number of times each token was sampled in 10000 samples.
+func sampleCounts(o *GenerateOptions) map[string]int {
+	r := rand.New(rand.NewSource(1))
+	c := candidates(samplingVariations)
+	results := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		results[o.sample(r, c)]++
+	}
+
+	return results
+}
+
+func TestCandidates(t *testing.T) {
+	c := candidates(samplingVariations)
+	ex := []candidate{
+		{"be", 5},
+		{"to", 3},
+		{"not", 2},
+		{"or", 2},
+	}
+	require.Equal(t, ex, c)
+	require.Empty(t, candidates(stores.Variations{}))
+}
+
+func TestGenerateOptionsValidate(t *testing.T) {
+	require.NoError(t, (&GenerateOptions{}).validate())
+	require.NoError(t, (&GenerateOptions{Temperature: 2, TopK: 3, TopP: 1}).validate())
+	require.Equal(t, ErrInvalidSampling, (&GenerateOptions{Temperature: -1}).validate())
+	require.Equal(t, ErrInvalidSampling, (&GenerateOptions{TopK: -1}).validate())
+	require.Equal(t, ErrInvalidSampling, (&GenerateOptions{TopP: -0.1}).validate())
+	require.Equal(t, ErrInvalidSampling, (&GenerateOptions{TopP: 1.1}).validate())
+}
+
+func TestGenerateOptionsSampling(t *testing.T) {
+	require.False(t, (&GenerateOptions{}).sampling())
+	require.False(t, (&GenerateOptions{Temperature: 1, TopP: 1, Sentences: 1}).sampling())
+	require.True(t, (&GenerateOptions{Greedy: true}).sampling())
+	require.True(t, (&GenerateOptions{TopK: 1}).sampling())
+	require.True(t, (&GenerateOptions{TopP: 0.5}).sampling())
+	require.True(t, (&GenerateOptions{Temperature: 0.5}).sampling())
+}
+
+func TestSample(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	require.Equal(t, "", (&GenerateOptions{}).sample(r, nil))
+
+	// Greedy always selects the most likely.
+	results := sampleCounts(&GenerateOptions{Greedy: true, TopK: 3})
+	require.Equal(t, map[string]int{"be": 10000}, results)
+
+	// Top-k only selects from the k most likely.
+	results = sampleCounts(&GenerateOptions{TopK: 2})
+	require.Equal(t, 2, len(results))
+	require.InDelta(t, 6250, results["be"], 300)
+	require.InDelta(t, 3750, results["to"], 300)
+
+	// Top-p keeps the smallest set of most likely candidates reaching p.
+	results = sampleCounts(&GenerateOptions{TopP: 0.4})
+	require.Equal(t, map[string]int{"be": 10000}, results)
+
+	results = sampleCounts(&GenerateOptions{TopP: 0.6})
+	require.Equal(t, 2, len(results))
+
+	// Unscaled sampling is proportional to the counts.
+	results = sampleCounts(&GenerateOptions{})
+	require.InDelta(t, 4167, results["be"], 300)
+	require.InDelta(t, 1667, results["or"], 300)
+
+	// Low temperatures favour the most likely, high temperatures flatten.
+	cold := sampleCounts(&GenerateOptions{Temperature: 0.25})
+	hot := sampleCounts(&GenerateOptions{Temperature: 4})
+	require.True(t, cold["be"] > results["be"])
+	require.True(t, hot["be"] < results["be"])
+	require.True(t, hot["or"] > results["or"])
+
+	// Extreme temperatures don't overflow.
+	results = sampleCounts(&GenerateOptions{Temperature: 0.0001})
+	require.Equal(t, map[string]int{"be": 10000}, results)
+}
+
+func TestGenerateSampling(t *testing.T) {
+	i := NewIndex(3, nil)
+	i.Parse("to be or not to be, that is the question. to be or to be.")
+
+	// Greedy generation is always the same.
+	b, err := i.Generate("to be", 4, &GenerateOptions{Greedy: true})
+	require.NoError(t, err)
+	require.Equal(t, "To be or not to be.", b)
+
+	_, err = i.Generate("to be", 4, &GenerateOptions{Temperature: -1})
+	require.Equal(t, ErrInvalidSampling, err)
+
+	i = NewIndex(3, &Options{
+		AllOrders: true,
+	})
+	i.Parse("to be or not to be, that is the question. to be or to be.")
+	b, err = i.Generate("unseen be", 2, &GenerateOptions{Greedy: true})
+	require.NoError(t, err)
+	require.Equal(t, "Unseen be or not.", b)
+}
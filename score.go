@@ -0,0 +1,165 @@
+package ngrams
+
+import (
+	"errors"
+	"math"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+var (
+	// ErrTooShort indicates that a text did not contain enough tokens to
+	// form a single ngram of the index's N length.
+	ErrTooShort = errors.New("not enough tokens to score")
+)
+
+// TokenScore contains the conditional probability of a single token given
+// the tokens which preceded it.
+type TokenScore struct {
+
+	// Token is the token which was scored.
+	Token string
+
+	// Context is the key of the preceding tokens the token was scored against,
+	// as joined by stores.JoinKey.
+	Context string
+
+	// Probability is the conditional probability of the token following the
+	// context, P(token|context).
+	Probability float64
+
+	// LogProb is the natural log of Probability. Tokens which were never
+	// indexed following the context will have a LogProb of -Inf.
+	LogProb float64
+}
+
+// Score contains the result of scoring a text against the index.
+type Score struct {
+
+	// Tokens contains the per-token conditional probabilities, in the order
+	// they appeared in the text.
+	Tokens []TokenScore
+
+	// LogProb is the total log-probability of the scored tokens (the sum of
+	// each TokenScore.LogProb).
+	LogProb float64
+
+	// Perplexity is the per-token perplexity of the text, exp(-LogProb/len(Tokens)).
+	// Lower is better; a text containing an unseen ngram has a perplexity of +Inf.
+	Perplexity float64
+}
+
+// Score returns the probability of each token in a text given its preceding
+// N-1 tokens, as well as the total log-probability and perplexity of the text.
+// Without a Smoother, probabilities are maximum likelihood estimates derived
+// from the number of times each variation was indexed, so the first N-1 tokens
+// of the text (which have no full context) are not scored. With a Smoother,
+// every token is scored against however many preceding tokens are available.
+// If the index uses sentence markers, the text is scored as complete sentences
+// with each end marker scored as a token. Unigrams have an empty context, so
+// an index of N=1 scores every token; if it doesn't record all orders, its
+// store must implement stores.Iterator to count the tokens.
+func (i *Index) Score(text string) (s *Score, err error) {
+	tokens := i.Tokenizer.Tokenize(text)
+	if i.SentenceMarkers {
+		tokens = i.markSentences(tokens)
+	}
+
+	// The context is always N-1 tokens long, so we need at least one more
+	// token than that to be able to score anything.
+	n := i.N - 1
+	if i.Smoother != nil {
+		n = 0
+	}
+
+	if len(tokens) <= n {
+		return nil, ErrTooShort
+	}
+
+	var unigrams stores.Variations
+	if i.N == 1 && !i.allOrders() {
+		unigrams, err = i.monograms()
+		if err != nil {
+			return nil, err
+		}
+	}
+	total := float64(unigrams.Total())
+
+	s = &Score{
+		Tokens: make([]TokenScore, 0, len(tokens)-n),
+	}
+
+	i = i.cached()
+
+	for j := n; j < len(tokens); j++ {
+		if tokens[j] == SentenceStart {
+			continue
+		}
+
+		var p float64
+		context := i.trimContext(tokens[:j])
+		switch {
+		case i.Smoother != nil:
+			p = i.Smoother.Probability(i, context, tokens[j])
+		case unigrams != nil:
+			if total > 0 {
+				p = float64(unigrams[tokens[j]]) / total
+			}
+		default:
+			p = i.probability(stores.JoinKey(context), tokens[j])
+		}
+
+		ts := TokenScore{
+			Token:       tokens[j],
+			Context:     stores.JoinKey(context),
+			Probability: p,
+			LogProb:     math.Log(p),
+		}
+
+		s.Tokens = append(s.Tokens, ts)
+		s.LogProb += ts.LogProb
+	}
+
+	s.Perplexity = math.Exp(-s.LogProb / float64(len(s.Tokens)))
+
+	return
+}
+
+// probability returns the maximum likelihood estimate of a future following a
+// key, based on the number of times each of the key's variations was indexed.
+func (i *Index) probability(key, future string) float64 {
+	ok, v := i.Store.Get(key)
+	if !ok {
+		return 0
+	}
+
+	total := v.Total()
+	if total == 0 {
+		return 0
+	}
+
+	return float64(v[future]) / float64(total)
+}
+
+// monograms returns the number of times each token was indexed by an index of
+// N=1 which doesn't record all orders. Such an index keys each token on itself
+// rather than on the empty context, so the variations of every key are summed.
+func (i *Index) monograms() (stores.Variations, error) {
+	it, ok := i.Store.(stores.Iterator)
+	if !ok {
+		return nil, ErrNotIterable
+	}
+
+	v := make(stores.Variations)
+	err := it.Each(func(key string, kv stores.Variations) error {
+		for future, n := range kv {
+			v[future] += n
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
@@ -0,0 +1,105 @@
+package ngrams
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+func TestScore(t *testing.T) {
+	i := NewIndex(3, nil)
+	i.Parse("to be or not to be that is the question")
+
+	s, err := i.Score("to be or not")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(s.Tokens))
+
+	require.Equal(t, "or", s.Tokens[0].Token)
+	require.Equal(t, "to be", s.Tokens[0].Context)
+	require.Equal(t, 0.5, s.Tokens[0].Probability)
+	require.Equal(t, math.Log(0.5), s.Tokens[0].LogProb)
+
+	require.Equal(t, "not", s.Tokens[1].Token)
+	require.Equal(t, "be or", s.Tokens[1].Context)
+	require.Equal(t, 1.0, s.Tokens[1].Probability)
+
+	require.Equal(t, math.Log(0.5), s.LogProb)
+	require.InDelta(t, math.Sqrt(2), s.Perplexity, 1e-9)
+
+	// A more predictable text should have a lower perplexity.
+	s2, err := i.Score("be or not to")
+	require.NoError(t, err)
+	require.Equal(t, 0.0, s2.LogProb)
+	require.Equal(t, 1.0, s2.Perplexity)
+	require.True(t, s2.Perplexity < s.Perplexity)
+
+	// Unseen ngrams have zero probability.
+	s, err = i.Score("to be a question")
+	require.NoError(t, err)
+	require.Equal(t, 0.0, s.Tokens[0].Probability)
+	require.True(t, math.IsInf(s.LogProb, -1))
+	require.True(t, math.IsInf(s.Perplexity, 1))
+
+	_, err = i.Score("to be")
+	require.Error(t, err)
+	require.Equal(t, ErrTooShort, err)
+}
+
+func TestScoreUnigrams(t *testing.T) {
+	for _, o := range []Options{{}, {AllOrders: true}} {
+		i := NewIndex(1, &o)
+		i.Parse("to be or not to be")
+
+		// Unigrams have an empty context, so every token is scored.
+		s, err := i.Score("to be not")
+		require.NoError(t, err)
+		require.Equal(t, 3, len(s.Tokens))
+		for j, p := range []float64{2.0 / 6.0, 2.0 / 6.0, 1.0 / 6.0} {
+			require.Equal(t, "", s.Tokens[j].Context)
+			require.InDelta(t, p, s.Tokens[j].Probability, 1e-9)
+		}
+		require.InDelta(t, math.Cbrt(54), s.Perplexity, 1e-9)
+
+		_, err = i.Score("")
+		require.Equal(t, ErrTooShort, err)
+	}
+
+	// Indexes which don't record all orders key each token on itself, so
+	// their stores must be iterable to count every token.
+	i := NewIndex(1, &Options{
+		Store: &plainStore{stores.NewMemoryStore()},
+	})
+	i.Parse("to be or not to be")
+	_, err := i.Score("to be")
+	require.Equal(t, ErrNotIterable, err)
+}
+
+func TestScoreEscapedContext(t *testing.T) {
+	i := NewIndex(3, &Options{
+		Tokenizer: new(fieldTokenizer),
+	})
+	i.Parse("a b|c|d")
+
+	// The context is the store key, so tokens containing spaces don't collide
+	// with the tokens either side of them.
+	s, err := i.Score("a b|c|d")
+	require.NoError(t, err)
+	require.Equal(t, stores.JoinKey([]string{"a b", "c"}), s.Tokens[0].Context)
+	require.NotEqual(t, stores.JoinKey([]string{"a", "b c"}), s.Tokens[0].Context)
+
+	ok, _ := i.Store.Get(s.Tokens[0].Context)
+	require.True(t, ok)
+}
+
+func TestProbability(t *testing.T) {
+	i := NewIndex(2, nil)
+	i.Parse("to be or not to be that is the question")
+
+	require.Equal(t, 1.0, i.probability("to", "be"))
+	require.Equal(t, 0.5, i.probability("be", "or"))
+	require.Equal(t, 0.0, i.probability("be", "question"))
+	require.Equal(t, 0.0, i.probability("missing", "be"))
+}
@@ -0,0 +1,104 @@
+package ngrams
+
+import (
+	tk "github.com/mochi-co/ngrams/tokenizers"
+)
+
+const (
+
+	// SentenceStart is the marker token indexed before the start of each
+	// sentence when sentence markers are in use.
+	SentenceStart = "<s>"
+
+	// SentenceEnd is the marker token indexed after the end of each sentence
+	// when sentence markers are in use.
+	SentenceEnd = "</s>"
+
+	// maxSentenceTokens is the maximum number of tokens that will be
+	// generated for each requested sentence, in case the generator never
+	// happens upon the end of a sentence.
+	maxSentenceTokens = 200
+)
+
+// marker inserts sentence start and end markers into a stream of tokens.
+// Each sentence is preceded by N-1 start markers so that the first token of
+// a sentence always has a full context, and followed by a single end marker.
+type marker struct {
+
+	// n is the number of start markers to insert before each sentence.
+	n int
+
+	// splitter identifies tokens which end a sentence. If the tokenizer can't
+	// split sentences, only the end of the stream ends a sentence.
+	splitter tk.SentenceSplitter
+
+	// open indicates that a sentence has been started but not yet ended.
+	open bool
+}
+
+// newMarker returns a sentence marker for the index.
+func (i *Index) newMarker() *marker {
+	m := &marker{
+		n: i.N - 1,
+	}
+
+	if s, ok := i.Tokenizer.(tk.SentenceSplitter); ok {
+		m.splitter = s
+	}
+
+	return m
+}
+
+// next returns the tokens that should be emitted for a token, including any
+// markers which start or end a sentence around it.
+func (m *marker) next(token string) []string {
+	var out []string
+	if !m.open {
+		out = make([]string, m.n, m.n+2)
+		for j := range out {
+			out[j] = SentenceStart
+		}
+		m.open = true
+	}
+
+	out = append(out, token)
+	if m.splitter != nil && m.splitter.EndsSentence(token) {
+		out = append(out, SentenceEnd)
+		m.open = false
+	}
+
+	return out
+}
+
+// close returns the tokens needed to end the stream, ending any sentence
+// which was left open.
+func (m *marker) close() []string {
+	if !m.open {
+		return nil
+	}
+
+	m.open = false
+	return []string{SentenceEnd}
+}
+
+// markSentences returns a copy of a slice of tokens with sentence markers
+// inserted around each sentence.
+func (i *Index) markSentences(tokens []string) []string {
+	m := i.newMarker()
+	out := make([]string, 0, len(tokens)+i.N)
+	for _, t := range tokens {
+		out = append(out, m.next(t)...)
+	}
+
+	return append(out, m.close()...)
+}
+
+// sentenceStart returns the context which begins every sentence.
+func (i *Index) sentenceStart() []string {
+	s := make([]string, i.N-1)
+	for j := range s {
+		s[j] = SentenceStart
+	}
+
+	return s
+}
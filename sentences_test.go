@@ -0,0 +1,144 @@
+package ngrams
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+const sentencesText = "the cat sat on the mat. the dog sat on the cat! where is the mat?"
+
+func TestMarkSentences(t *testing.T) {
+	i := NewIndex(3, &Options{
+		SentenceMarkers: true,
+	})
+
+	tokens := i.markSentences(i.Tokenizer.Tokenize("the cat sat. the dog"))
+	ex := []string{
+		"<s>", "<s>", "the", "cat", "sat", ".", "</s>",
+		"<s>", "<s>", "the", "dog", "</s>",
+	}
+	require.Equal(t, ex, tokens)
+	require.Empty(t, i.markSentences([]string{}))
+
+	// Without a sentence splitter, only the end of the stream ends a sentence.
+	i = NewIndex(2, &Options{
+		SentenceMarkers: true,
+		Tokenizer: &MockTokenizer{
+			tokens: []string{"the", "cat", ".", "the", "dog"},
+		},
+	})
+	tokens = i.markSentences(i.Tokenizer.Tokenize(""))
+	require.Equal(t, []string{"<s>", "the", "cat", ".", "the", "dog", "</s>"}, tokens)
+}
+
+func TestParseSentenceMarkers(t *testing.T) {
+	i := NewIndex(3, &Options{
+		SentenceMarkers: true,
+		Store:           new(MockStore),
+	})
+	_, err := i.Parse("the cat sat. the dog")
+	require.NoError(t, err)
+	ex := []string{
+		"<s> <s> the",
+		"<s> the cat",
+		"the cat sat",
+		"cat sat .",
+		"sat . </s>",
+		"<s> <s> the",
+		"<s> the dog",
+		"the dog </s>",
+	}
+	require.Equal(t, ex, i.Store.(*MockStore).added)
+
+	i.Store = new(MockStore)
+	err = i.Read(strings.NewReader("the cat sat. the dog"))
+	require.NoError(t, err)
+	require.Equal(t, ex, i.Store.(*MockStore).added)
+
+	i.Store = &MockStore{
+		errAdd: true,
+	}
+	_, err = i.Parse("the cat sat. the dog")
+	require.Error(t, err)
+
+	// The closing marker can fail to be added too.
+	i = NewIndex(3, &Options{
+		SentenceMarkers: true,
+		Store:           new(MockStore),
+	})
+	g := i.newIngester(context.Background(), stores.AddContext)
+	require.NoError(t, g.push("the"))
+	i.Store.(*MockStore).errAdd = true
+	require.Error(t, g.close())
+
+	// Start markers are never stored as a future, even with all orders.
+	i = NewIndex(3, &Options{
+		SentenceMarkers: true,
+		AllOrders:       true,
+	})
+	i.Parse(sentencesText)
+	require.Equal(t, int64(3), i.Frequency(SentenceEnd))
+	require.Equal(t, int64(0), i.Frequency(SentenceStart))
+
+	ok, v := i.Lookup([]string{SentenceStart, SentenceStart})
+	require.True(t, ok)
+	require.Equal(t, stores.Variations{"the": 2, "where": 1}, v)
+}
+
+func TestBabbleSentences(t *testing.T) {
+	for _, o := range []*Options{
+		{SentenceMarkers: true},
+		{SentenceMarkers: true, AllOrders: true},
+	} {
+		i := NewIndex(3, o)
+		i.Parse(sentencesText)
+
+		b, err := i.BabbleSentences("", 1)
+		require.NoError(t, err)
+		tokens := i.Tokenizer.Tokenize(strings.ToLower(b))
+		require.Contains(t, []string{"the", "where"}, tokens[0])
+		require.Contains(t, []string{".", "!", "?"}, tokens[len(tokens)-1])
+		require.NotContains(t, b, SentenceStart)
+		require.NotContains(t, b, SentenceEnd)
+
+		// The text only contains the end of sentence markers, so each
+		// sentence contains exactly one.
+		b, err = i.BabbleSentences("", 3)
+		require.NoError(t, err)
+		require.Equal(t, 3, strings.Count(b, ".")+strings.Count(b, "!")+strings.Count(b, "?"))
+
+		// Babble also starts at the start of a sentence.
+		b, err = i.Babble("", 4)
+		require.NoError(t, err)
+		tokens = i.Tokenizer.Tokenize(strings.ToLower(b))
+		require.Contains(t, []string{"the", "where"}, tokens[0])
+	}
+
+	i := NewIndex(3, nil)
+	i.Parse(sentencesText)
+	_, err := i.BabbleSentences("", 1)
+	require.Equal(t, ErrNoSentenceMarkers, err)
+}
+
+func TestScoreSentenceMarkers(t *testing.T) {
+	i := NewIndex(3, &Options{
+		SentenceMarkers: true,
+	})
+	i.Parse(sentencesText)
+
+	s, err := i.Score("where is the mat?")
+	require.NoError(t, err)
+	require.Equal(t, 6, len(s.Tokens))
+	require.Equal(t, "<s> <s>", s.Tokens[0].Context)
+	require.Equal(t, "where", s.Tokens[0].Token)
+	require.Equal(t, SentenceEnd, s.Tokens[5].Token)
+	require.InDelta(t, 1.0/3.0, s.Tokens[0].Probability, 1e-9)
+
+	_, err = i.Score("")
+	require.Equal(t, ErrTooShort, err)
+}
@@ -0,0 +1,344 @@
+package ngrams
+
+import (
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+const (
+
+	// continuationPrefix is prepended to the keys of continuation counts so
+	// they can never collide with the key of an indexed context.
+	continuationPrefix = "\x1e"
+
+	// defaultBackoffAlpha is the default multiplier applied each time stupid
+	// backoff falls back to a lower order.
+	defaultBackoffAlpha float64 = 0.4
+
+	// defaultKatzDiscount is the default absolute discount subtracted from each
+	// count by Katz backoff.
+	defaultKatzDiscount float64 = 0.5
+
+	// defaultKneserNeyDiscount is the default absolute discount subtracted from
+	// each count by Kneser-Ney smoothing.
+	defaultKneserNeyDiscount float64 = 0.75
+)
+
+// Smoother estimates the conditional probability of a token following a
+// context, consulting lower-order contexts when the full context was rarely
+// or never indexed. Smoothers rely on the index recording every order of ngram
+// from unigrams up to N, which it will do whenever a Smoother is set.
+type Smoother interface {
+
+	// Probability returns the estimated probability of the token following
+	// the context, P(token|context). The context will be no more than N-1
+	// tokens long, and may be empty.
+	Probability(i *Index, context []string, token string) float64
+}
+
+// continuationKey returns the store key for the continuation counts of an ngram.
+func continuationKey(tokens []string) string {
+	return continuationPrefix + stores.JoinKey(tokens)
+}
+
+// smoothingCache contains the totals which smoothers derive from the store
+// during a single call, such as scoring a text, so that each context is only
+// counted once however many tokens are scored against it. The store must not
+// change while the cache is in use.
+type smoothingCache struct {
+
+	// lookups contains the variations of each context which was looked up,
+	// keyed on the context.
+	lookups map[string]cachedVariations
+
+	// continuations contains the continuation counts of the tokens which
+	// followed each lower-order context, keyed on the context.
+	continuations map[string]*continuationCounts
+
+	// weights contains the Katz backoff weight of each context, keyed on the
+	// context.
+	weights map[string]float64
+}
+
+// cachedVariations contains the variations of a context and their total.
+type cachedVariations struct {
+	ok    bool
+	v     stores.Variations
+	total float64
+}
+
+// continuationCounts contains the continuation counts of the tokens which
+// followed a context, along with their total and the number of tokens which
+// had any.
+type continuationCounts struct {
+	counts map[string]float64
+	total  float64
+	types  float64
+}
+
+// cached returns a copy of the index which caches the totals its smoother
+// derives from the store. The copy is only used for a single call which
+// doesn't change the store.
+func (i *Index) cached() *Index {
+	c := *i
+	c.cache = &smoothingCache{
+		lookups:       make(map[string]cachedVariations),
+		continuations: make(map[string]*continuationCounts),
+		weights:       make(map[string]float64),
+	}
+
+	return &c
+}
+
+// lookupTotal returns the variations which have been indexed following a
+// context, as per Lookup, along with their total.
+func (i *Index) lookupTotal(context []string) (bool, stores.Variations, float64) {
+	key := stores.JoinKey(context)
+	if i.cache != nil {
+		if c, ok := i.cache.lookups[key]; ok {
+			return c.ok, c.v, c.total
+		}
+	}
+
+	ok, v := i.Store.Get(key)
+	total := float64(v.Total())
+	if i.cache != nil {
+		i.cache.lookups[key] = cachedVariations{ok, v, total}
+	}
+
+	return ok, v, total
+}
+
+// unigram returns the add-one smoothed probability of a token, which is used
+// as the base case for each smoother so that tokens which were never indexed
+// still receive a small, non-zero probability.
+func (i *Index) unigram(token string) float64 {
+	_, v, total := i.lookupTotal(nil)
+	return (float64(v[token]) + 1) / (total + float64(len(v)) + 1)
+}
+
+// StupidBackoff is a simple, unnormalized backoff strategy which uses the
+// relative frequency of the longest indexed context, multiplied by Alpha for
+// each order it had to back off. The resulting scores are fast to compute but
+// are not true probabilities, as they do not sum to 1.
+// See Brants et al. 2007, "Large Language Models in Machine Translation".
+type StupidBackoff struct {
+
+	// Alpha is the multiplier applied each time the lookup backs off to a
+	// lower order.
+	Alpha float64
+}
+
+// NewStupidBackoff returns a stupid backoff smoother. If alpha is 0, the
+// default of 0.4 will be used.
+func NewStupidBackoff(alpha float64) *StupidBackoff {
+	if alpha <= 0 {
+		alpha = defaultBackoffAlpha
+	}
+
+	return &StupidBackoff{
+		Alpha: alpha,
+	}
+}
+
+// Probability returns the stupid backoff score of a token following a context.
+func (s *StupidBackoff) Probability(i *Index, context []string, token string) float64 {
+	context = i.trimContext(context)
+	if len(context) == 0 {
+		return i.unigram(token)
+	}
+
+	ok, v, total := i.lookupTotal(context)
+	if ok && v[token] > 0 {
+		return float64(v[token]) / total
+	}
+
+	return s.Alpha * s.Probability(i, context[1:], token)
+}
+
+// Katz is a Katz backoff smoother. The counts of each indexed variation are
+// reduced by an absolute discount, and the probability mass freed by the
+// discount is redistributed to unseen tokens in proportion to their
+// probability under the next lower order.
+type Katz struct {
+
+	// Discount is subtracted from the count of each indexed variation. It must
+	// be between 0 and 1.
+	Discount float64
+}
+
+// NewKatzBackoff returns a Katz backoff smoother. If discount is not between
+// 0 and 1, the default of 0.5 will be used.
+func NewKatzBackoff(discount float64) *Katz {
+	if discount <= 0 || discount >= 1 {
+		discount = defaultKatzDiscount
+	}
+
+	return &Katz{
+		Discount: discount,
+	}
+}
+
+// Probability returns the Katz backoff probability of a token following a context.
+func (k *Katz) Probability(i *Index, context []string, token string) float64 {
+	context = i.trimContext(context)
+	if len(context) == 0 {
+		return i.unigram(token)
+	}
+
+	// If the context was never indexed there's nothing to discount, so all of
+	// the probability comes from the lower order.
+	ok, v, total := i.lookupTotal(context)
+	if !ok || len(v) == 0 {
+		return k.Probability(i, context[1:], token)
+	}
+
+	if c := v[token]; c > 0 {
+		return (float64(c) - k.Discount) / total
+	}
+
+	return k.weight(i, context, v, total) * k.Probability(i, context[1:], token)
+}
+
+// weight returns the backoff weight of a context, which normalizes the mass
+// left over by the discount against the mass the lower order assigns to the
+// tokens which weren't seen in the context.
+func (k *Katz) weight(i *Index, context []string, v stores.Variations, total float64) float64 {
+	key := stores.JoinKey(context)
+	if i.cache != nil {
+		if w, ok := i.cache.weights[key]; ok {
+			return w
+		}
+	}
+
+	var seen, lower float64
+	for future, c := range v {
+		seen += (float64(c) - k.Discount) / total
+		lower += k.Probability(i, context[1:], future)
+	}
+
+	var w float64
+	if lower < 1 {
+		w = (1 - seen) / (1 - lower)
+	}
+
+	if i.cache != nil {
+		i.cache.weights[key] = w
+	}
+
+	return w
+}
+
+// KneserNey is an interpolated Kneser-Ney smoother. The highest order uses
+// absolutely discounted counts, and each lower order uses continuation counts;
+// the number of distinct tokens which preceded an ngram, rather than how many
+// times it occurred. This favours tokens which appear in many different
+// contexts over tokens which are only frequent in a few.
+type KneserNey struct {
+
+	// Discount is subtracted from the count of each indexed variation. It must
+	// be between 0 and 1.
+	Discount float64
+}
+
+// NewKneserNey returns an interpolated Kneser-Ney smoother. If discount is not
+// between 0 and 1, the default of 0.75 will be used.
+func NewKneserNey(discount float64) *KneserNey {
+	if discount <= 0 || discount >= 1 {
+		discount = defaultKneserNeyDiscount
+	}
+
+	return &KneserNey{
+		Discount: discount,
+	}
+}
+
+// Probability returns the Kneser-Ney probability of a token following a context.
+func (k *KneserNey) Probability(i *Index, context []string, token string) float64 {
+	context = i.trimContext(context)
+	if len(context) == 0 {
+		return k.lower(i, context, token)
+	}
+
+	ok, v, total := i.lookupTotal(context)
+	if !ok || len(v) == 0 {
+		return k.lower(i, context[1:], token)
+	}
+
+	gamma := k.Discount * float64(len(v)) / total
+
+	return k.discount(float64(v[token]))/total + gamma*k.lower(i, context[1:], token)
+}
+
+// lower returns the probability of a token following a lower-order context,
+// using continuation counts in place of raw counts.
+func (k *KneserNey) lower(i *Index, context []string, token string) float64 {
+	c := k.continuations(i, context)
+
+	// Without any continuation counts there's nothing to interpolate, so fall
+	// back to the next order down.
+	if c.total == 0 {
+		if len(context) == 0 {
+			return i.unigram(token)
+		}
+
+		return k.lower(i, context[1:], token)
+	}
+
+	// The lowest order interpolates with a uniform distribution over the
+	// vocabulary (plus one for unseen tokens).
+	var next float64
+	if len(context) == 0 {
+		_, u, _ := i.lookupTotal(nil)
+		next = 1 / (float64(len(u)) + 1)
+	} else {
+		next = k.lower(i, context[1:], token)
+	}
+
+	gamma := k.Discount * c.types / c.total
+
+	return k.discount(c.counts[token])/c.total + gamma*next
+}
+
+// continuations returns the continuation counts of the tokens which followed a
+// context. The candidate tokens are any which were indexed after the context,
+// and their continuation counts are the number of distinct tokens which
+// preceded the context and the candidate.
+func (k *KneserNey) continuations(i *Index, context []string) *continuationCounts {
+	key := stores.JoinKey(context)
+	if i.cache != nil {
+		if c, ok := i.cache.continuations[key]; ok {
+			return c
+		}
+	}
+
+	_, v, _ := i.lookupTotal(context)
+	c := &continuationCounts{
+		counts: make(map[string]float64, len(v)),
+	}
+
+	for future := range v {
+		_, cv := i.Continuations(append(context[:len(context):len(context)], future))
+		if len(cv) == 0 {
+			continue
+		}
+
+		c.counts[future] = float64(len(cv))
+		c.total += float64(len(cv))
+		c.types++
+	}
+
+	if i.cache != nil {
+		i.cache.continuations[key] = c
+	}
+
+	return c
+}
+
+// discount returns a count reduced by the absolute discount, floored at 0.
+func (k *KneserNey) discount(c float64) float64 {
+	if c-k.Discount < 0 {
+		return 0
+	}
+
+	return c - k.Discount
+}
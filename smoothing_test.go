@@ -0,0 +1,232 @@
+package ngrams
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+const smoothingText = "to be or not to be that is the question whether tis nobler in the mind to suffer"
+
+// sumProbabilities returns the sum of the probabilities of every token in the
+// vocabulary, plus an unseen token, following a context.
+func sumProbabilities(i *Index, context []string) float64 {
+	_, vocab := i.Lookup(nil)
+	var sum float64
+	for token := range vocab {
+		sum += i.Smoother.Probability(i, context, token)
+	}
+
+	return sum + i.Smoother.Probability(i, context, "unseen")
+}
+
+func TestSmootherRecordsAllOrders(t *testing.T) {
+	i := NewIndex(3, &Options{
+		Smoother: NewStupidBackoff(0),
+	})
+	_, err := i.Parse("to be or not to be")
+	require.NoError(t, err)
+
+	ok, v := i.Lookup(nil)
+	require.True(t, ok)
+	require.Equal(t, stores.Variations{"to": 2, "be": 2, "or": 1, "not": 1}, v)
+
+	ok, v = i.Lookup([]string{"to"})
+	require.True(t, ok)
+	require.Equal(t, stores.Variations{"be": 2}, v)
+
+	ok, v = i.Lookup([]string{"to", "be"})
+	require.True(t, ok)
+	require.Equal(t, stores.Variations{"or": 1}, v)
+
+	// Continuations are only recorded for Kneser-Ney.
+	ok, _ = i.Continuations([]string{"be"})
+	require.False(t, ok)
+
+	// Reading should record exactly the same counts as parsing.
+	r := NewIndex(3, &Options{
+		Smoother: NewStupidBackoff(0),
+	})
+	err = r.Read(strings.NewReader("to be or not to be"))
+	require.NoError(t, err)
+	require.Equal(t, i.Store, r.Store)
+}
+
+func TestKneserNeyRecordsContinuations(t *testing.T) {
+	i := NewIndex(3, &Options{
+		Smoother: NewKneserNey(0),
+	})
+	i.Parse("to be or not to be")
+
+	ok, v := i.Continuations([]string{"be"})
+	require.True(t, ok)
+	require.Equal(t, stores.Variations{"to": 2}, v)
+
+	ok, v = i.Continuations([]string{"be", "or"})
+	require.True(t, ok)
+	require.Equal(t, stores.Variations{"to": 1}, v)
+}
+
+func TestNewSmoothers(t *testing.T) {
+	require.Equal(t, defaultBackoffAlpha, NewStupidBackoff(0).Alpha)
+	require.Equal(t, 0.5, NewStupidBackoff(0.5).Alpha)
+	require.Equal(t, defaultKatzDiscount, NewKatzBackoff(0).Discount)
+	require.Equal(t, defaultKatzDiscount, NewKatzBackoff(1).Discount)
+	require.Equal(t, 0.2, NewKatzBackoff(0.2).Discount)
+	require.Equal(t, defaultKneserNeyDiscount, NewKneserNey(0).Discount)
+	require.Equal(t, 0.2, NewKneserNey(0.2).Discount)
+}
+
+func TestStupidBackoff(t *testing.T) {
+	i := NewIndex(3, &Options{
+		Smoother: NewStupidBackoff(0.4),
+	})
+	i.Parse(smoothingText)
+	s := i.Smoother
+
+	// Seen trigram uses the relative frequency.
+	require.Equal(t, 0.5, s.Probability(i, []string{"to", "be"}, "or"))
+
+	// Unseen trigram, seen bigram.
+	require.InDelta(t, 0.4*1.0/3.0, s.Probability(i, []string{"not", "to"}, "suffer"), 1e-9)
+
+	// Unseen everything backs off to the unigram.
+	p := s.Probability(i, []string{"to", "be"}, "unseen")
+	require.True(t, p > 0)
+	require.InDelta(t, 0.4*0.4*i.unigram("unseen"), p, 1e-9)
+
+	// Contexts longer than N-1 are trimmed.
+	require.Equal(t, 0.5, s.Probability(i, []string{"ignored", "to", "be"}, "or"))
+}
+
+func TestKatz(t *testing.T) {
+	i := NewIndex(3, &Options{
+		Smoother: NewKatzBackoff(0.5),
+	})
+	i.Parse(smoothingText)
+	s := i.Smoother
+
+	require.Equal(t, 0.25, s.Probability(i, []string{"to", "be"}, "or"))
+	require.True(t, s.Probability(i, []string{"to", "be"}, "suffer") > 0)
+
+	for _, context := range [][]string{{"to", "be"}, {"the"}, {"unseen", "context"}, {}} {
+		require.InDelta(t, 1.0, sumProbabilities(i, context), 1e-9)
+	}
+}
+
+func TestKneserNey(t *testing.T) {
+	i := NewIndex(3, &Options{
+		Smoother: NewKneserNey(0.75),
+	})
+	i.Parse(smoothingText)
+	s := i.Smoother
+
+	require.True(t, s.Probability(i, []string{"to", "be"}, "or") > s.Probability(i, []string{"to", "be"}, "suffer"))
+	require.True(t, s.Probability(i, []string{"to", "be"}, "unseen") > 0)
+
+	for _, context := range [][]string{{"to", "be"}, {"the"}, {"unseen", "context"}, {}} {
+		require.InDelta(t, 1.0, sumProbabilities(i, context), 1e-9)
+	}
+
+	// Without any continuation counts, the lowest order falls back to the
+	// add-one unigram.
+	j := NewIndex(3, &Options{
+		Smoother: NewStupidBackoff(0),
+	})
+	j.Parse(smoothingText)
+	require.Equal(t, j.unigram("to"), NewKneserNey(0).Probability(j, []string{}, "to"))
+}
+
+func TestSmoothingCache(t *testing.T) {
+	for _, s := range []Smoother{NewStupidBackoff(0), NewKatzBackoff(0), NewKneserNey(0)} {
+		i := NewIndex(3, &Options{
+			Smoother: s,
+		})
+		i.Parse(smoothingText)
+
+		// The cached copy gives exactly the same probabilities however many
+		// times each context is consulted.
+		c := i.cached()
+		for _, context := range [][]string{{"to", "be"}, {"the"}, {"unseen", "context"}, {}} {
+			for j := 0; j < 2; j++ {
+				for _, token := range []string{"or", "be", "suffer", "unseen"} {
+					require.Equal(t, s.Probability(i, context, token), s.Probability(c, context, token))
+				}
+			}
+		}
+
+		require.NotEmpty(t, c.cache.lookups)
+		require.Nil(t, i.cache)
+	}
+}
+
+func TestScoreSmoothed(t *testing.T) {
+	i := NewIndex(3, &Options{
+		Smoother: NewKneserNey(0),
+	})
+	i.Parse(smoothingText)
+
+	s, err := i.Score("to be a question")
+	require.NoError(t, err)
+	require.Equal(t, 4, len(s.Tokens))
+	require.Equal(t, "", s.Tokens[0].Context)
+	require.Equal(t, "to", s.Tokens[1].Context)
+	require.Equal(t, "to be", s.Tokens[2].Context)
+	require.Equal(t, "be a", s.Tokens[3].Context)
+	require.False(t, math.IsInf(s.LogProb, -1))
+	require.False(t, math.IsInf(s.Perplexity, 1))
+
+	// Seen text should be more likely than unseen text.
+	s2, err := i.Score("to be or not")
+	require.NoError(t, err)
+	require.True(t, s2.Perplexity < s.Perplexity)
+
+	_, err = i.Score("")
+	require.Equal(t, ErrTooShort, err)
+}
+
+func TestBabbleBackoff(t *testing.T) {
+	i := NewIndex(3, &Options{
+		Smoother: NewStupidBackoff(0),
+	})
+	i.Parse(smoothingText)
+
+	// An unseen seed should back off to known contexts and keep generating.
+	b, err := i.Babble("something unseen", 10)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(b, "Something unseen"))
+	require.Equal(t, 13, len(i.Tokenizer.Tokenize(b))) // 2 seed, 10 generated, and a full stop from Format.
+
+	b, err = i.Babble("", 10)
+	require.NoError(t, err)
+	require.NotEmpty(t, b)
+
+	i = NewIndex(3, &Options{
+		Smoother: NewStupidBackoff(0),
+	})
+	_, err = i.Babble("", 10)
+	require.Equal(t, ErrEmptyIndex, err)
+}
+
+func TestBackoff(t *testing.T) {
+	i := NewIndex(3, &Options{
+		Smoother: NewStupidBackoff(0),
+	})
+	i.Parse("to be or not to be")
+
+	context, v := i.backoff([]string{"not", "to"})
+	require.Equal(t, []string{"not", "to"}, context)
+	require.Equal(t, stores.Variations{"be": 1}, v)
+
+	context, v = i.backoff([]string{"unseen", "to"})
+	require.Equal(t, []string{"to"}, context)
+	require.Equal(t, stores.Variations{"be": 2}, v)
+
+	context, v = i.backoff([]string{"unseen"})
+	require.Equal(t, []string{}, context)
+	require.Equal(t, int64(6), v.Total())
+}
@@ -0,0 +1,433 @@
+package ngrams
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+	"sort"
+
+	stores "github.com/mochi-co/ngrams/stores"
+	tk "github.com/mochi-co/ngrams/tokenizers"
+)
+
+const (
+
+	// snapshotMagic identifies a saved index.
+	snapshotMagic = "NGRAMIDX"
+
+	// snapshotVersion is the version of the saved index format. Version 1
+	// saved keys which were not escaped, so they are migrated when loaded.
+	snapshotVersion byte = 2
+
+	// maxSnapshotString is the longest string which will be read from a saved
+	// index, so a corrupt length can't exhaust memory.
+	maxSnapshotString = 1 << 24
+)
+
+// Flags recording the index options in a saved index.
+const (
+	snapshotAllOrders byte = 1 << iota
+	snapshotSentenceMarkers
+)
+
+// Smoothers which can be recorded in a saved index.
+const (
+	snapshotNoSmoother byte = iota
+	snapshotStupidBackoff
+	snapshotKatz
+	snapshotKneserNey
+)
+
+var (
+	// ErrNotIterable indicates that an index can't be saved because its store
+	// doesn't implement stores.Iterator.
+	ErrNotIterable = errors.New("store can not be iterated")
+
+	// ErrInvalidSnapshot indicates that data is not a saved index, is corrupt,
+	// or was saved by an unsupported version.
+	ErrInvalidSnapshot = errors.New("invalid saved index")
+
+	// ErrChecksumMismatch indicates that a saved index failed its checksum.
+	ErrChecksumMismatch = errors.New("saved index checksum mismatch")
+
+	// ErrUnknownTokenizer indicates that the tokenizer a saved index was
+	// created with can't be recreated, either because it's unknown or didn't
+	// identify itself, so one must be given in the options.
+	ErrUnknownTokenizer = errors.New("unknown tokenizer")
+
+	// ErrTokenizerMismatch indicates that the tokenizer given to load a saved
+	// index tokenizes differently to the one it was created with.
+	ErrTokenizerMismatch = errors.New("tokenizer does not match saved index")
+)
+
+// Save writes the index to w in a versioned, gzip-compressed binary format,
+// which can be read by Load. N, the index options, the tokenizer's identity
+// and every ngram in the store are saved. The store must implement
+// stores.Iterator.
+func (i *Index) Save(w io.Writer) error {
+	it, ok := i.Store.(stores.Iterator)
+	if !ok {
+		return ErrNotIterable
+	}
+
+	_, err := w.Write(append([]byte(snapshotMagic), snapshotVersion))
+	if err != nil {
+		return err
+	}
+
+	// Everything following the magic bytes and version is compressed, and
+	// followed by a CRC32 checksum of the uncompressed data.
+	gz := gzip.NewWriter(w)
+	h := crc32.NewIEEE()
+	sw := &snapshotWriter{
+		w: bufio.NewWriter(io.MultiWriter(gz, h)),
+	}
+
+	var flags byte
+	if i.allOrders() {
+		flags |= snapshotAllOrders
+	}
+	if i.SentenceMarkers {
+		flags |= snapshotSentenceMarkers
+	}
+
+	var id string
+	if t, ok := i.Tokenizer.(tk.Identifier); ok {
+		id = t.ID()
+	}
+
+	kind, param := snapshotSmoother(i.Smoother)
+	sw.uvarint(uint64(i.N))
+	sw.byte(flags)
+	sw.string(id)
+	sw.byte(kind)
+	sw.uint64(math.Float64bits(param))
+
+	// Each key is preceded by a 1, and the end of the keys is marked by a 0.
+	// The variations are sorted so the same index is always saved the same way.
+	err = it.Each(func(key string, v stores.Variations) error {
+		futures := make([]string, 0, len(v))
+		for f := range v {
+			futures = append(futures, f)
+		}
+		sort.Strings(futures)
+
+		sw.byte(1)
+		sw.string(key)
+		sw.uvarint(uint64(len(futures)))
+		for _, f := range futures {
+			sw.string(f)
+			sw.uvarint(uint64(v[f]))
+		}
+
+		return sw.err
+	})
+	if err != nil {
+		return err
+	}
+
+	sw.byte(0)
+	if sw.err != nil {
+		return sw.err
+	}
+
+	err = sw.w.Flush()
+	if err != nil {
+		return err
+	}
+
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], h.Sum32())
+	_, err = gz.Write(sum[:])
+	if err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// Load reads an index written by Save. The ngrams are added to the store in
+// the options, so an index saved from one store can be loaded into any other.
+// If no tokenizer is given, the tokenizer the index was saved with is
+// recreated, or ErrUnknownTokenizer is returned if it doesn't implement
+// tokenizers.Identifier, or isn't one of the package's own. If no smoother is given, the smoother the index was saved with
+// is used. Nothing is added to the store unless the whole index is read and
+// passes its checksum. The options may be nil.
+func Load(r io.Reader, o *Options) (*Index, error) {
+	header := make([]byte, len(snapshotMagic)+1)
+	_, err := io.ReadFull(r, header)
+	if err != nil || string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, ErrInvalidSnapshot
+	}
+
+	version := header[len(snapshotMagic)]
+	if version == 0 || version > snapshotVersion {
+		return nil, ErrInvalidSnapshot
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, ErrInvalidSnapshot
+	}
+	defer gz.Close()
+
+	sr := &snapshotReader{
+		r: bufio.NewReader(gz),
+		h: crc32.NewIEEE(),
+	}
+
+	n := sr.uvarint()
+	flags := sr.byte()
+	id := sr.string()
+	kind := sr.byte()
+	param := math.Float64frombits(sr.uint64())
+	if sr.err != nil {
+		return nil, sr.err
+	}
+
+	if n == 0 || n > math.MaxInt32 {
+		return nil, ErrInvalidSnapshot
+	}
+
+	opts := new(Options)
+	if o != nil {
+		*opts = *o
+	}
+
+	opts.AllOrders = flags&snapshotAllOrders != 0
+	opts.SentenceMarkers = flags&snapshotSentenceMarkers != 0
+	if opts.Smoother == nil {
+		opts.Smoother, err = loadSmoother(kind, param)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Make sure the tokenizer will tokenize text in the same way as the one
+	// the index was saved with.
+	if opts.Tokenizer == nil {
+		t, ok := tk.FromID(id)
+		if !ok {
+			return nil, ErrUnknownTokenizer
+		}
+		opts.Tokenizer = t
+	} else if t, ok := opts.Tokenizer.(tk.Identifier); ok && id != "" && t.ID() != id {
+		return nil, ErrTokenizerMismatch
+	}
+
+	// The ngrams are only added to the store once the checksum has passed, so
+	// a corrupt snapshot never leaves partial data in a persistent store.
+	i := NewIndex(int(n), opts)
+	var entries []stores.Entry
+	for sr.byte() == 1 {
+		key := sr.string()
+		if version == 1 {
+			key = migrateKey(key)
+		}
+
+		variations := sr.uvarint()
+		for j := uint64(0); j < variations && sr.err == nil; j++ {
+			future := sr.string()
+			count := sr.uvarint()
+			if sr.err != nil {
+				break
+			}
+
+			entries = append(entries, stores.Entry{
+				Key:    key,
+				Future: future,
+				Count:  int64(count),
+			})
+		}
+	}
+
+	if sr.err != nil {
+		return nil, sr.err
+	}
+
+	// The checksum itself isn't part of the checksummed data.
+	sum := sr.h.Sum32()
+	var b [4]byte
+	_, err = io.ReadFull(sr.r, b[:])
+	if err != nil {
+		return nil, ErrInvalidSnapshot
+	}
+
+	if binary.LittleEndian.Uint32(b[:]) != sum {
+		return nil, ErrChecksumMismatch
+	}
+
+	err = i.addEntries(context.Background(), entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// snapshotSmoother returns the kind and parameter of a smoother for saving.
+func snapshotSmoother(s Smoother) (byte, float64) {
+	switch t := s.(type) {
+	case *StupidBackoff:
+		return snapshotStupidBackoff, t.Alpha
+	case *Katz:
+		return snapshotKatz, t.Discount
+	case *KneserNey:
+		return snapshotKneserNey, t.Discount
+	}
+
+	return snapshotNoSmoother, 0
+}
+
+// loadSmoother returns a smoother from the kind and parameter it was saved with.
+func loadSmoother(kind byte, param float64) (Smoother, error) {
+	switch kind {
+	case snapshotNoSmoother:
+		return nil, nil
+	case snapshotStupidBackoff:
+		return NewStupidBackoff(param), nil
+	case snapshotKatz:
+		return NewKatzBackoff(param), nil
+	case snapshotKneserNey:
+		return NewKneserNey(param), nil
+	}
+
+	return nil, ErrInvalidSnapshot
+}
+
+// snapshotWriter writes the values in a saved index. After the first error,
+// nothing more is written and the error is kept in err.
+type snapshotWriter struct {
+
+	// w is the writer being written to.
+	w *bufio.Writer
+
+	// err is the first error which occurred while writing.
+	err error
+}
+
+// write writes b unless an error has already occurred.
+func (s *snapshotWriter) write(b []byte) {
+	if s.err != nil {
+		return
+	}
+
+	_, s.err = s.w.Write(b)
+}
+
+// byte writes a single byte.
+func (s *snapshotWriter) byte(b byte) {
+	s.write([]byte{b})
+}
+
+// uvarint writes a varint-encoded unsigned integer.
+func (s *snapshotWriter) uvarint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	s.write(buf[:binary.PutUvarint(buf[:], v)])
+}
+
+// uint64 writes a fixed-length unsigned integer.
+func (s *snapshotWriter) uint64(v uint64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	s.write(buf[:])
+}
+
+// string writes a length-prefixed string.
+func (s *snapshotWriter) string(v string) {
+	s.uvarint(uint64(len(v)))
+	s.write([]byte(v))
+}
+
+// snapshotReader reads the values in a saved index, adding every byte read to
+// a checksum. After the first error, zero values are returned and the error
+// is kept in err.
+type snapshotReader struct {
+
+	// r is the reader being read from.
+	r *bufio.Reader
+
+	// h is the checksum of the bytes read so far.
+	h hash.Hash32
+
+	// err is the first error which occurred while reading.
+	err error
+}
+
+// ReadByte reads a single byte, so the reader can be used to read varints.
+func (s *snapshotReader) ReadByte() (byte, error) {
+	b, err := s.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	s.h.Write([]byte{b})
+	return b, nil
+}
+
+// read fills b unless an error has already occurred.
+func (s *snapshotReader) read(b []byte) {
+	if s.err != nil {
+		return
+	}
+
+	_, err := io.ReadFull(s.r, b)
+	if err != nil {
+		s.err = ErrInvalidSnapshot
+		return
+	}
+
+	s.h.Write(b)
+}
+
+// byte reads a single byte.
+func (s *snapshotReader) byte() byte {
+	var b [1]byte
+	s.read(b[:])
+	return b[0]
+}
+
+// uvarint reads a varint-encoded unsigned integer.
+func (s *snapshotReader) uvarint() uint64 {
+	if s.err != nil {
+		return 0
+	}
+
+	v, err := binary.ReadUvarint(s)
+	if err != nil {
+		s.err = ErrInvalidSnapshot
+		return 0
+	}
+
+	return v
+}
+
+// uint64 reads a fixed-length unsigned integer.
+func (s *snapshotReader) uint64() uint64 {
+	var b [8]byte
+	s.read(b[:])
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+// string reads a length-prefixed string.
+func (s *snapshotReader) string() string {
+	l := s.uvarint()
+	if s.err != nil {
+		return ""
+	}
+
+	if l > maxSnapshotString {
+		s.err = ErrInvalidSnapshot
+		return ""
+	}
+
+	b := make([]byte, l)
+	s.read(b)
+	return string(b)
+}
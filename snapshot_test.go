@@ -0,0 +1,198 @@
+package ngrams
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	stores "github.com/mochi-co/ngrams/stores"
+	tk "github.com/mochi-co/ngrams/tokenizers"
+)
+
+// storeGrams returns a copy of every ngram in an iterable store.
+func storeGrams(t *testing.T, s stores.Store) stores.Grams {
+	g := make(stores.Grams)
+	err := s.(stores.Iterator).Each(func(key string, v stores.Variations) error {
+		g[key] = make(stores.Variations, len(v))
+		for future, n := range v {
+			g[key][future] = n
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	return g
+}
+
+func TestSaveLoad(t *testing.T) {
+	for _, o := range []*Options{
+		{},
+		{SentenceMarkers: true},
+		{Smoother: NewKneserNey(0.5), Tokenizer: tk.NewDefaultWordTokenizer(false)},
+		{Smoother: NewKatzBackoff(0.25)},
+		{Smoother: NewStupidBackoff(0.3), SentenceMarkers: true},
+	} {
+		i := NewIndex(4, o)
+		i.Parse(smoothingText)
+
+		var b bytes.Buffer
+		err := i.Save(&b)
+		require.NoError(t, err)
+
+		l, err := Load(bytes.NewReader(b.Bytes()), nil)
+		require.NoError(t, err)
+		require.Equal(t, i.N, l.N)
+		require.Equal(t, i.allOrders(), l.allOrders())
+		require.Equal(t, i.SentenceMarkers, l.SentenceMarkers)
+		require.Equal(t, i.Smoother, l.Smoother)
+		require.Equal(t, i.Tokenizer, l.Tokenizer)
+		require.Equal(t, storeGrams(t, i.Store), storeGrams(t, l.Store))
+
+		// Saving the same index again gives exactly the same output.
+		var b2 bytes.Buffer
+		err = l.Save(&b2)
+		require.NoError(t, err)
+		require.Equal(t, b.Bytes(), b2.Bytes())
+	}
+}
+
+func TestLoadIntoStore(t *testing.T) {
+	i := NewIndex(3, nil)
+	i.Parse(smoothingText)
+
+	var b bytes.Buffer
+	err := i.Save(&b)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "ngrams")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, err := stores.NewFileStore(filepath.Join(dir, "store.log"))
+	require.NoError(t, err)
+
+	l, err := Load(&b, &Options{
+		Store: s,
+	})
+	require.NoError(t, err)
+	defer l.Close()
+	require.Equal(t, s, l.Store)
+	require.Equal(t, storeGrams(t, i.Store), storeGrams(t, l.Store))
+}
+
+func TestSaveNotIterable(t *testing.T) {
+	i := NewIndex(3, &Options{
+		Store: new(MockStore),
+	})
+
+	err := i.Save(new(bytes.Buffer))
+	require.Equal(t, ErrNotIterable, err)
+}
+
+func TestLoadTokenizer(t *testing.T) {
+	i := NewIndex(3, nil)
+	i.Parse(smoothingText)
+
+	var b bytes.Buffer
+	err := i.Save(&b)
+	require.NoError(t, err)
+
+	_, err = Load(bytes.NewReader(b.Bytes()), &Options{
+		Tokenizer: tk.NewDefaultWordTokenizer(false),
+	})
+	require.Equal(t, ErrTokenizerMismatch, err)
+
+	// Tokenizers which can't identify themselves are trusted.
+	l, err := Load(bytes.NewReader(b.Bytes()), &Options{
+		Tokenizer: new(MockTokenizer),
+	})
+	require.NoError(t, err)
+	require.Equal(t, new(MockTokenizer), l.Tokenizer)
+
+	// Indexes saved with an unknown tokenizer need one to be given.
+	i.Tokenizer = &identifiedTokenizer{}
+	b.Reset()
+	err = i.Save(&b)
+	require.NoError(t, err)
+	_, err = Load(bytes.NewReader(b.Bytes()), nil)
+	require.Equal(t, ErrUnknownTokenizer, err)
+
+	_, err = Load(bytes.NewReader(b.Bytes()), &Options{
+		Tokenizer: &identifiedTokenizer{},
+	})
+	require.NoError(t, err)
+
+	// So do indexes saved with a tokenizer which didn't identify itself.
+	i.Tokenizer = new(MockTokenizer)
+	b.Reset()
+	err = i.Save(&b)
+	require.NoError(t, err)
+	_, err = Load(bytes.NewReader(b.Bytes()), nil)
+	require.Equal(t, ErrUnknownTokenizer, err)
+
+	_, err = Load(bytes.NewReader(b.Bytes()), &Options{
+		Tokenizer: new(MockTokenizer),
+	})
+	require.NoError(t, err)
+}
+
+// identifiedTokenizer is a tokenizer with an ID which isn't in the tokenizers
+// package.
+type identifiedTokenizer struct {
+	MockTokenizer
+}
+
+func (tk *identifiedTokenizer) ID() string {
+	return "identified"
+}
+
+func TestLoadInvalid(t *testing.T) {
+	i := NewIndex(3, nil)
+	i.Parse(smoothingText)
+
+	var b bytes.Buffer
+	err := i.Save(&b)
+	require.NoError(t, err)
+	saved := b.Bytes()
+
+	_, err = Load(bytes.NewReader([]byte("not a saved index")), nil)
+	require.Equal(t, ErrInvalidSnapshot, err)
+
+	// Unsupported versions can't be loaded.
+	v := append([]byte{}, saved...)
+	v[len(snapshotMagic)]++
+	_, err = Load(bytes.NewReader(v), nil)
+	require.Equal(t, ErrInvalidSnapshot, err)
+
+	// Truncated data can't be loaded.
+	_, err = Load(bytes.NewReader(saved[:len(saved)/2]), nil)
+	require.Error(t, err)
+
+	// Changes to the uncompressed data fail the checksum.
+	gz, err := gzip.NewReader(bytes.NewReader(saved[len(snapshotMagic)+1:]))
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(gz)
+	require.NoError(t, err)
+
+	data[len(data)-10]++
+	var c bytes.Buffer
+	c.Write(saved[:len(snapshotMagic)+1])
+	w := gzip.NewWriter(&c)
+	w.Write(data)
+	w.Close()
+
+	corrupt := c.Bytes()
+	_, err = Load(bytes.NewReader(corrupt), nil)
+	require.Equal(t, ErrChecksumMismatch, err)
+
+	// Nothing is added to the store unless the checksum passes.
+	s := stores.NewMemoryStore()
+	_, err = Load(bytes.NewReader(corrupt), &Options{Store: s})
+	require.Equal(t, ErrChecksumMismatch, err)
+	require.Empty(t, storeGrams(t, s))
+}
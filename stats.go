@@ -0,0 +1,258 @@
+package ngrams
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+	"strings"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+const (
+
+	// defaultStatsTop is the number of most frequent ngrams returned by Stats.
+	defaultStatsTop = 10
+)
+
+// Stats contains statistics about what an index has learned.
+type Stats struct {
+
+	// Tokens is the number of tokens which were indexed. If the index records
+	// all orders this is every token, otherwise it's every token which
+	// followed a full context of N-1 tokens.
+	Tokens int64 `json:"tokens"`
+
+	// Vocabulary is the number of distinct tokens which were indexed. The
+	// sentence start and end markers aren't words of the text, so neither is
+	// counted.
+	Vocabulary int `json:"vocabulary"`
+
+	// Keys is the number of keys in the store, of every order.
+	Keys int `json:"keys"`
+
+	// Variations is the number of variations in the store, of every order.
+	Variations int `json:"variations"`
+
+	// CountOfCounts contains the number of ngrams of order N which were
+	// indexed exactly c times, keyed on c.
+	CountOfCounts map[int64]int `json:"count_of_counts"`
+
+	// BranchingFactor is the mean number of variations of each key of N-1
+	// tokens.
+	BranchingFactor float64 `json:"branching_factor"`
+
+	// Entropy summarises the entropy of the variations of each key of N-1
+	// tokens.
+	Entropy EntropyStats `json:"entropy"`
+
+	// Top contains the most frequent ngrams of order N, most frequent first.
+	Top []NgramCount `json:"top"`
+}
+
+// EntropyStats summarises the entropy, in bits, of the variations of a set of
+// keys. A key with a single variation has an entropy of 0.
+type EntropyStats struct {
+
+	// Min is the lowest entropy of any key.
+	Min float64 `json:"min"`
+
+	// Max is the highest entropy of any key.
+	Max float64 `json:"max"`
+
+	// Mean is the mean entropy of the keys.
+	Mean float64 `json:"mean"`
+
+	// Conditional is the mean entropy of the keys weighted by how often each
+	// was indexed, which is the entropy of the next token given the context.
+	Conditional float64 `json:"conditional"`
+}
+
+// NgramCount is an ngram and the number of times it was indexed.
+type NgramCount struct {
+
+	// Ngram contains the tokens of the ngram.
+	Ngram []string `json:"ngram"`
+
+	// Count is the number of times the ngram was indexed.
+	Count int64 `json:"count"`
+}
+
+// Stats returns statistics about what the index has learned, including the
+// 10 most frequent ngrams. The store must implement stores.Iterator.
+func (i *Index) Stats() (*Stats, error) {
+	return i.StatsTop(defaultStatsTop)
+}
+
+// StatsTop returns statistics about what the index has learned, as per Stats,
+// including the top most frequent ngrams. Only the top ngrams are kept while
+// the store is iterated, so any number can be asked for.
+func (i *Index) StatsTop(top int) (*Stats, error) {
+	it, ok := i.Store.(stores.Iterator)
+	if !ok {
+		return nil, ErrNotIterable
+	}
+
+	s := &Stats{
+		CountOfCounts: make(map[int64]int),
+		Entropy: EntropyStats{
+			Min: math.Inf(1),
+		},
+	}
+
+	vocabulary := make(map[string]bool)
+	var keys, variations int
+	var total int64
+	var ngrams topNgrams
+
+	err := it.Each(func(key string, v stores.Variations) error {
+		if strings.HasPrefix(key, continuationPrefix) {
+			return nil
+		}
+
+		s.Keys++
+		s.Variations += len(v)
+
+		tokens := stores.SplitKey(key)
+		if len(tokens) == 0 {
+			s.Tokens = v.Total()
+		}
+
+		for future := range v {
+			vocabulary[future] = true
+		}
+
+		// The remaining statistics are only for ngrams of order N, as the lower
+		// orders of an index recording all orders would be counted repeatedly.
+		if len(tokens) != i.N-1 {
+			return nil
+		}
+
+		for _, t := range tokens {
+			vocabulary[t] = true
+		}
+
+		t := v.Total()
+		h := entropy(v, t)
+		keys++
+		total += t
+		s.Entropy.Min = math.Min(s.Entropy.Min, h)
+		s.Entropy.Max = math.Max(s.Entropy.Max, h)
+		s.Entropy.Mean += h
+		s.Entropy.Conditional += h * float64(t)
+
+		variations += len(v)
+		for future, n := range v {
+			s.CountOfCounts[n]++
+			ngrams.offer(tokens, future, n, top)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	delete(vocabulary, SentenceStart)
+	delete(vocabulary, SentenceEnd)
+	s.Vocabulary = len(vocabulary)
+	if !i.allOrders() {
+		s.Tokens = total
+	}
+
+	if keys == 0 {
+		s.Entropy.Min = 0
+		return s, nil
+	}
+
+	s.BranchingFactor = float64(variations) / float64(keys)
+	s.Entropy.Mean /= float64(keys)
+	s.Entropy.Conditional /= float64(total)
+
+	sort.Slice(ngrams, func(a, b int) bool {
+		return ngrams.before(a, b)
+	})
+
+	s.Top = make([]NgramCount, len(ngrams))
+	for j, r := range ngrams {
+		s.Top[j] = r.NgramCount
+	}
+
+	return s, nil
+}
+
+// rankedNgram is an ngram and its count, along with its key, which orders
+// ngrams with the same count.
+type rankedNgram struct {
+	NgramCount
+	key string
+}
+
+// topNgrams is a heap of the most frequent ngrams, with the least frequent at
+// the root so it can be replaced by a more frequent ngram in logarithmic time.
+type topNgrams []rankedNgram
+
+// offer adds an ngram of a context and future indexed n times to the heap if
+// it's among the top most frequent which have been offered.
+func (h *topNgrams) offer(context []string, future string, n int64, top int) {
+	if top <= 0 || (len(*h) == top && n < (*h)[0].Count) {
+		return
+	}
+
+	ngram := append(append(make([]string, 0, len(context)+1), context...), future)
+	r := rankedNgram{
+		NgramCount: NgramCount{
+			Ngram: ngram,
+			Count: n,
+		},
+		key: stores.JoinKey(ngram),
+	}
+
+	if len(*h) < top {
+		heap.Push(h, r)
+		return
+	}
+
+	if r.Count > (*h)[0].Count || (r.Count == (*h)[0].Count && r.key < (*h)[0].key) {
+		(*h)[0] = r
+		heap.Fix(h, 0)
+	}
+}
+
+// before returns true if the ngram at a is more frequent than the ngram at b,
+// or as frequent with a lower key.
+func (h topNgrams) before(a, b int) bool {
+	if h[a].Count != h[b].Count {
+		return h[a].Count > h[b].Count
+	}
+
+	return h[a].key < h[b].key
+}
+
+func (h topNgrams) Len() int           { return len(h) }
+func (h topNgrams) Less(a, b int) bool { return h.before(b, a) }
+func (h topNgrams) Swap(a, b int)      { h[a], h[b] = h[b], h[a] }
+
+func (h *topNgrams) Push(x interface{}) {
+	*h = append(*h, x.(rankedNgram))
+}
+
+func (h *topNgrams) Pop() interface{} {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
+
+// entropy returns the entropy, in bits, of a set of variations indexed total
+// times.
+func entropy(v stores.Variations, total int64) (h float64) {
+	for _, n := range v {
+		if n > 0 {
+			p := float64(n) / float64(total)
+			h -= p * math.Log2(p)
+		}
+	}
+
+	return
+}
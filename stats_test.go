@@ -0,0 +1,86 @@
+package ngrams
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+func TestStats(t *testing.T) {
+	i := NewIndex(2, nil)
+	i.Parse("to be or not to be or to go")
+
+	s, err := i.StatsTop(2)
+	require.NoError(t, err)
+	require.Equal(t, int64(8), s.Tokens)
+	require.Equal(t, 5, s.Vocabulary)
+	require.Equal(t, 4, s.Keys)
+	require.Equal(t, 6, s.Variations)
+	require.Equal(t, map[int64]int{1: 4, 2: 2}, s.CountOfCounts)
+	require.Equal(t, 1.5, s.BranchingFactor)
+
+	// "or" has an entropy of 1 bit, and "to" of h bits over 3 ngrams.
+	h := -(2.0/3*math.Log2(2.0/3) + 1.0/3*math.Log2(1.0/3))
+	require.Equal(t, 0.0, s.Entropy.Min)
+	require.Equal(t, 1.0, s.Entropy.Max)
+	require.InDelta(t, (h+1)/4, s.Entropy.Mean, 1e-9)
+	require.InDelta(t, (3*h+2)/8, s.Entropy.Conditional, 1e-9)
+
+	require.Equal(t, []NgramCount{
+		{Ngram: []string{"be", "or"}, Count: 2},
+		{Ngram: []string{"to", "be"}, Count: 2},
+	}, s.Top)
+
+	s, err = i.Stats()
+	require.NoError(t, err)
+	require.Equal(t, 6, len(s.Top))
+	require.Equal(t, []string{"to", "go"}, s.Top[5].Ngram)
+
+	s, err = i.StatsTop(0)
+	require.NoError(t, err)
+	require.Empty(t, s.Top)
+}
+
+func TestStatsSentenceMarkers(t *testing.T) {
+	i := NewIndex(2, &Options{
+		SentenceMarkers: true,
+	})
+	i.Parse("to be. or not")
+
+	// Neither sentence marker is counted as a word.
+	s, err := i.Stats()
+	require.NoError(t, err)
+	require.Equal(t, 5, s.Vocabulary)
+}
+
+func TestStatsAllOrders(t *testing.T) {
+	i := NewIndex(3, &Options{
+		Smoother: NewKneserNey(0.75),
+	})
+	i.Parse("to be or not to be")
+
+	s, err := i.Stats()
+	require.NoError(t, err)
+	require.Equal(t, int64(6), s.Tokens)
+	require.Equal(t, 4, s.Vocabulary)
+
+	// Unigram, bigram and trigram keys, but not continuation counts.
+	require.Equal(t, 1+4+4, s.Keys)
+	require.Equal(t, map[int64]int{1: 4}, s.CountOfCounts)
+	require.Equal(t, 1.0, s.BranchingFactor)
+	require.Equal(t, EntropyStats{}, s.Entropy)
+}
+
+func TestStatsEmpty(t *testing.T) {
+	s, err := NewIndex(3, nil).Stats()
+	require.NoError(t, err)
+	require.Equal(t, &Stats{CountOfCounts: map[int64]int{}}, s)
+
+	_, err = NewIndex(3, &Options{
+		Store: &plainStore{stores.NewMemoryStore()},
+	}).Stats()
+	require.Equal(t, ErrNotIterable, err)
+}
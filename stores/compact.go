@@ -0,0 +1,371 @@
+package stores
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// NewCompactStore returns an in-memory ngram store which interns tokens as
+// integer IDs. Ngrams added to the store are not persisted when the service
+// restarts.
+func NewCompactStore() Store {
+	return &CompactStore{
+		vocabulary: NewVocabulary(),
+		grams:      make(map[string]*compactVariations),
+	}
+}
+
+// CompactStore is an in-memory ngram store which holds each distinct token
+// only once. Keys are held as packed tuples of token IDs, and variations as
+// sorted slices of IDs and counts, rather than as strings and maps, which uses
+// less than half the memory of MemoryStore for large indexes. Variations are
+// returned as a new map on every Get, so MemoryStore is faster for small
+// indexes. It complies with Store interface.
+type CompactStore struct {
+
+	// sync implements a mutex for concurrent read/write.
+	sync.RWMutex
+
+	// vocabulary interns the tokens of the keys and variations.
+	vocabulary *Vocabulary
+
+	// grams contains the indexed variations, keyed on packed key.
+	grams map[string]*compactVariations
+
+	// keySet contains each packed key in grams, so a random key can be
+	// selected reproducibly and quickly. The position of each key is held in
+	// its variations rather than in the keySet.
+	keySet
+}
+
+// compactVariations contains the token IDs of the variations of a key, sorted
+// by ID, and the number of times each was indexed, along with the position of
+// the key in the store's keySet.
+type compactVariations struct {
+	futures []uint32
+	counts  []int64
+	pos     int
+}
+
+// add adds n to the count of a variation.
+func (c *compactVariations) add(future uint32, n int64) {
+	j := sort.Search(len(c.futures), func(j int) bool {
+		return c.futures[j] >= future
+	})
+
+	if j < len(c.futures) && c.futures[j] == future {
+		c.counts[j] += n
+		return
+	}
+
+	c.futures = append(c.futures, 0)
+	copy(c.futures[j+1:], c.futures[j:])
+	c.futures[j] = future
+
+	c.counts = append(c.counts, 0)
+	copy(c.counts[j+1:], c.counts[j:])
+	c.counts[j] = n
+}
+
+// Add adds an ngram to the store.
+func (s *CompactStore) Add(key, future string) error {
+	return s.AddN(key, future, 1)
+}
+
+// AddN adds an ngram to the store as though it had been added count times.
+// Counts less than 1 are ignored.
+func (s *CompactStore) AddN(key, future string, count int64) error {
+	if count < 1 {
+		return nil
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.add(key, future, count)
+
+	return nil
+}
+
+// AddBatch adds every entry to the store, taking the lock only once. Counts less
+// than 1 are ignored.
+func (s *CompactStore) AddBatch(entries []Entry) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, e := range entries {
+		if e.Count > 0 {
+			s.add(e.Key, e.Future, e.Count)
+		}
+	}
+
+	return nil
+}
+
+// add adds an ngram to the store as though it had been added count times. The
+// caller must hold the lock.
+func (s *CompactStore) add(key, future string, count int64) {
+	packed := s.intern(key)
+	id := s.vocabulary.Intern(future)
+
+	c, ok := s.grams[packed]
+	if !ok {
+		c = &compactVariations{
+			pos: s.appendKey(packed, 0),
+		}
+		s.grams[packed] = c
+	}
+
+	c.add(id, count)
+	s.weights.add(c.pos, count)
+}
+
+// removePacked removes a packed key and its variations from the store. The
+// caller must hold the lock.
+func (s *CompactStore) removePacked(packed string, c *compactVariations) {
+	delete(s.grams, packed)
+	if moved, ok := s.removeAt(c.pos); ok {
+		s.grams[moved].pos = c.pos
+	}
+}
+
+// remove removes a single count of a variation, returning false if the
+// variation was not found.
+func (c *compactVariations) remove(future uint32) bool {
+	j := sort.Search(len(c.futures), func(j int) bool {
+		return c.futures[j] >= future
+	})
+
+	if j == len(c.futures) || c.futures[j] != future {
+		return false
+	}
+
+	c.counts[j]--
+	if c.counts[j] == 0 {
+		c.futures = append(c.futures[:j], c.futures[j+1:]...)
+		c.counts = append(c.counts[:j], c.counts[j+1:]...)
+	}
+
+	return true
+}
+
+// Remove removes a single count of a variation from the store, removing the
+// variation when it reaches zero, and the key when it has no variations left.
+// The tokens of the ngram remain in the vocabulary.
+func (s *CompactStore) Remove(key, future string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	packed, ok := s.pack(key)
+	if !ok {
+		return nil
+	}
+
+	c, ok := s.grams[packed]
+	if !ok {
+		return nil
+	}
+
+	id, ok := s.vocabulary.ID(future)
+	if !ok || !c.remove(id) {
+		return nil
+	}
+
+	if len(c.futures) == 0 {
+		s.removePacked(packed, c)
+		return nil
+	}
+
+	s.weights.add(c.pos, -1)
+
+	return nil
+}
+
+// Get gets an ngram variation from the store.
+func (s *CompactStore) Get(key string) (bool, Variations) {
+	s.RLock()
+	defer s.RUnlock()
+
+	packed, ok := s.pack(key)
+	if !ok {
+		return false, nil
+	}
+
+	c, ok := s.grams[packed]
+	if !ok {
+		return false, nil
+	}
+
+	return true, s.variations(c)
+}
+
+// Delete removes an ngram from the store. The tokens of the ngram remain in the
+// vocabulary.
+func (s *CompactStore) Delete(key string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	packed, ok := s.pack(key)
+	if !ok {
+		return nil
+	}
+
+	if c, ok := s.grams[packed]; ok {
+		s.removePacked(packed, c)
+	}
+
+	return nil
+}
+
+// Each calls fn for every key in the store and its variations, stopping at the
+// first error. The store is locked for reading while iterating, so fn must not
+// add to or delete from it.
+func (s *CompactStore) Each(fn func(key string, v Variations) error) error {
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, packed := range s.keys {
+		err := fn(s.unpack(packed), s.variations(s.grams[packed]))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Len returns the number of keys in the store.
+func (s *CompactStore) Len() int {
+	s.RLock()
+	defer s.RUnlock()
+
+	return len(s.keys)
+}
+
+// ScanPrefix calls fn for every key beginning with prefix and its variations,
+// in key order, stopping at the first error. The store is locked for reading
+// while scanning, so fn must not add to or delete from it.
+func (s *CompactStore) ScanPrefix(prefix string, fn func(key string, v Variations) error) error {
+	s.RLock()
+	defer s.RUnlock()
+
+	// Packed keys are not ordered by their tokens, so every key is unpacked to
+	// be matched against the prefix.
+	matched := make(map[string]string)
+	var keys []string
+	for _, packed := range s.keys {
+		k := s.unpack(packed)
+		if strings.HasPrefix(k, prefix) {
+			matched[k] = packed
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		err := fn(k, s.variations(s.grams[matched[k]]))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Vocabulary returns the number of distinct tokens interned by the store.
+func (s *CompactStore) Vocabulary() int {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.vocabulary.Len()
+}
+
+// Any returns a random ngram from the store, selected uniformly.
+func (s *CompactStore) Any() (k string, v Variations, err error) {
+	return s.AnyFrom(defaultRand)
+}
+
+// AnyFrom returns a random ngram from the store, selected uniformly using r.
+func (s *CompactStore) AnyFrom(r *rand.Rand) (k string, v Variations, err error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	packed, ok := s.uniform(r)
+	if ok {
+		k, v = s.unpack(packed), s.variations(s.grams[packed])
+	}
+
+	return
+}
+
+// AnyWeightedFrom returns a random ngram from the store, selected using r with
+// a probability proportional to the number of variations indexed for it.
+func (s *CompactStore) AnyWeightedFrom(r *rand.Rand) (k string, v Variations, err error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	packed, ok := s.weighted(r)
+	if ok {
+		k, v = s.unpack(packed), s.variations(s.grams[packed])
+	}
+
+	return
+}
+
+// Close gracefully disconnects the store. Because this is just in-memory,
+// it will do nothing and return no errors.
+func (s *CompactStore) Close() error {
+	return nil
+}
+
+// intern packs the tokens of a key into a string of 4-byte token IDs,
+// interning any tokens which have not been seen.
+func (s *CompactStore) intern(key string) string {
+	tokens := strings.Split(key, " ")
+	b := make([]byte, 4*len(tokens))
+	for j, t := range tokens {
+		binary.LittleEndian.PutUint32(b[4*j:], s.vocabulary.Intern(t))
+	}
+
+	return string(b)
+}
+
+// pack packs the tokens of a key into a string of 4-byte token IDs, or returns
+// false if any of the tokens have not been interned.
+func (s *CompactStore) pack(key string) (string, bool) {
+	tokens := strings.Split(key, " ")
+	b := make([]byte, 4*len(tokens))
+	for j, t := range tokens {
+		id, ok := s.vocabulary.ID(t)
+		if !ok {
+			return "", false
+		}
+		binary.LittleEndian.PutUint32(b[4*j:], id)
+	}
+
+	return string(b), true
+}
+
+// unpack returns the key of a packed string of token IDs.
+func (s *CompactStore) unpack(packed string) string {
+	tokens := make([]string, len(packed)/4)
+	for j := range tokens {
+		tokens[j], _ = s.vocabulary.Token(binary.LittleEndian.Uint32([]byte(packed[4*j : 4*j+4])))
+	}
+
+	return strings.Join(tokens, " ")
+}
+
+// variations returns the variations of a key as a new Variations map.
+func (s *CompactStore) variations(c *compactVariations) Variations {
+	v := make(Variations, len(c.futures))
+	for j, id := range c.futures {
+		future, _ := s.vocabulary.Token(id)
+		v[future] = c.counts[j]
+	}
+
+	return v
+}
@@ -0,0 +1,244 @@
+package stores
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCompactStore(t *testing.T) {
+	m := NewCompactStore()
+	require.NotNil(t, m)
+	require.IsType(t, new(CompactStore), m)
+}
+
+func TestCompactAddGet(t *testing.T) {
+	m := NewCompactStore()
+
+	ok, v := m.Get("to be")
+	require.False(t, ok)
+	require.Nil(t, v)
+
+	m.Add("to be", "or")
+	m.Add("to be", "not")
+	m.Add("to be", "or")
+	m.Add("be or", "not")
+	m.Add("", "to")
+
+	ok, v = m.Get("to be")
+	require.True(t, ok)
+	require.Equal(t, Variations{"or": 2, "not": 1}, v)
+
+	ok, v = m.Get("")
+	require.True(t, ok)
+	require.Equal(t, Variations{"to": 1}, v)
+
+	// Known tokens in an unknown order are not a key.
+	ok, _ = m.Get("be to")
+	require.False(t, ok)
+
+	// Each token is interned once across keys and variations.
+	require.Equal(t, 5, m.(*CompactStore).Vocabulary())
+	require.Equal(t, 3, m.(*CompactStore).Len())
+}
+
+func TestCompactVariationsSorted(t *testing.T) {
+	c := new(compactVariations)
+	c.add(5, 1)
+	c.add(2, 1)
+	c.add(9, 2)
+	c.add(5, 3)
+	require.Equal(t, []uint32{2, 5, 9}, c.futures)
+	require.Equal(t, []int64{1, 4, 2}, c.counts)
+}
+
+func TestCompactDelete(t *testing.T) {
+	m := NewCompactStore().(*CompactStore)
+	m.Add("to be", "or")
+	m.Add("be or", "not")
+	m.Add("or not", "to")
+
+	require.NoError(t, m.Delete("to be"))
+	ok, _ := m.Get("to be")
+	require.False(t, ok)
+	require.Equal(t, 2, m.Len())
+	require.Equal(t, int64(2), m.weights.total())
+
+	require.NoError(t, m.Delete("missing key"))
+	require.Equal(t, 2, m.Len())
+}
+
+func TestCompactEachScanPrefix(t *testing.T) {
+	m := NewCompactStore().(*CompactStore)
+	m.Add("to be", "or")
+	m.Add("be or", "not")
+	m.Add("to bed", "and")
+
+	got := map[string]Variations{}
+	err := m.Each(func(key string, v Variations) error {
+		got[key] = v
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]Variations{
+		"to be":  {"or": 1},
+		"be or":  {"not": 1},
+		"to bed": {"and": 1},
+	}, got)
+
+	var keys []string
+	err = m.ScanPrefix("to be", func(key string, v Variations) error {
+		keys = append(keys, key)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"to be", "to bed"}, keys)
+
+	var _ Iterator = m
+	var _ PrefixScanner = m
+	var _ Sizer = m
+}
+
+func TestCompactAnyFrom(t *testing.T) {
+	m := NewCompactStore().(*CompactStore)
+	k, v, err := m.AnyFrom(rand.New(rand.NewSource(1)))
+	require.NoError(t, err)
+	require.Empty(t, k)
+	require.Nil(t, v)
+
+	m.Add("to be", "or")
+	for i := 0; i < 9; i++ {
+		m.Add("be or", "not")
+	}
+
+	k, v, err = m.Any()
+	require.NoError(t, err)
+	require.True(t, k == "to be" || k == "be or")
+	require.NotEmpty(t, v)
+
+	// Selections match those of a memory store with the same ngrams.
+	mem := NewMemoryStore().(*MemoryStore)
+	mem.Add("to be", "or")
+	for i := 0; i < 9; i++ {
+		mem.Add("be or", "not")
+	}
+
+	r1 := rand.New(rand.NewSource(42))
+	r2 := rand.New(rand.NewSource(42))
+	for i := 0; i < 20; i++ {
+		a, av, _ := m.AnyWeightedFrom(r1)
+		b, bv, _ := mem.AnyWeightedFrom(r2)
+		require.Equal(t, b, a)
+		require.Equal(t, bv, av)
+	}
+
+	var _ Sampler = m
+	var _ WeightedSampler = m
+}
+
+func TestCompactRemove(t *testing.T) {
+	m := NewCompactStore().(*CompactStore)
+	m.Add("to be", "or")
+	m.Add("to be", "or")
+	m.Add("to be", "that")
+	m.Add("be or", "not")
+
+	require.NoError(t, m.Remove("to be", "or"))
+	_, v := m.Get("to be")
+	require.Equal(t, Variations{"or": 1, "that": 1}, v)
+
+	require.NoError(t, m.Remove("to be", "that"))
+	_, v = m.Get("to be")
+	require.Equal(t, Variations{"or": 1}, v)
+
+	// Missing variations and keys are ignored.
+	require.NoError(t, m.Remove("to be", "not"))
+	require.NoError(t, m.Remove("to be", "unknown"))
+	require.NoError(t, m.Remove("unknown key", "or"))
+	require.Equal(t, int64(2), m.weights.total())
+
+	require.NoError(t, m.Remove("be or", "not"))
+	ok, _ := m.Get("be or")
+	require.False(t, ok)
+	require.Equal(t, 1, m.Len())
+	require.Equal(t, int64(1), m.weights.total())
+
+	var _ Remover = m
+}
+
+func TestCompactAddN(t *testing.T) {
+	m := NewCompactStore().(*CompactStore)
+	require.NoError(t, m.AddN("to be", "or", 5))
+	require.NoError(t, m.Add("to be", "or"))
+	require.NoError(t, m.AddN("to be", "that", 2))
+	require.NoError(t, m.AddN("be or", "not", 0))
+
+	_, v := m.Get("to be")
+	require.Equal(t, Variations{"or": 6, "that": 2}, v)
+	require.Equal(t, 1, m.Len())
+	require.Equal(t, int64(8), m.weights.total())
+
+	var _ CountAdder = m
+}
+
+func TestCompactAddBatch(t *testing.T) {
+	m := NewCompactStore().(*CompactStore)
+	require.NoError(t, m.AddBatch([]Entry{
+		{Key: "to be", Future: "or", Count: 2},
+		{Key: "be or", Future: "not", Count: 1},
+		{Key: "to be", Future: "that", Count: -1},
+	}))
+
+	_, v := m.Get("to be")
+	require.Equal(t, Variations{"or": 2}, v)
+	require.Equal(t, 2, m.Len())
+	require.Equal(t, int64(3), m.weights.total())
+
+	var _ Batcher = m
+}
+
+// heapInUse returns the bytes allocated on the heap after collecting garbage.
+func heapInUse() int64 {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return int64(m.HeapAlloc)
+}
+
+// storeSize returns the bytes used by a store after adding every trigram of a
+// text to it. The heap can shrink while the store is filled, so the size is
+// signed rather than wrapping around.
+func storeSize(t *testing.T, fn func() Store, words []string) int64 {
+	before := heapInUse()
+	s := fn()
+	for j := 2; j < len(words); j++ {
+		require.NoError(t, s.Add(JoinKey(words[j-2:j]), words[j]))
+	}
+	after := heapInUse()
+	runtime.KeepAlive(s)
+
+	return after - before
+}
+
+func TestCompactMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping memory comparison in short mode")
+	}
+
+	data, err := ioutil.ReadFile("../training/pride-prejudice.txt")
+	require.NoError(t, err)
+	words := strings.Fields(string(data))
+
+	// Indexing a novel into the compact store uses less than half the memory
+	// of the memory store.
+	memory := storeSize(t, NewMemoryStore, words)
+	compact := storeSize(t, NewCompactStore, words)
+	t.Logf("memory store: %d KiB, compact store: %d KiB", memory/1024, compact/1024)
+	require.True(t, memory > 0)
+	require.True(t, compact > 0)
+	require.True(t, compact < memory/2)
+}
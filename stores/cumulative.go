@@ -0,0 +1,69 @@
+package stores
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// Cumulative contains the cumulative weights of a set of variations, so that
+// a weighted random variation can be selected in logarithmic time rather than
+// by scanning every variation. A Cumulative is not changed once created, so it
+// is safe for concurrent use and can be cached until the variations change.
+type Cumulative struct {
+
+	// futures contains the variations in sorted order.
+	futures []string
+
+	// weights contains the sum of the number of times each variation and all
+	// the variations before it were indexed.
+	weights []int64
+}
+
+// NewCumulative returns the cumulative weights of a set of variations.
+func NewCumulative(v Variations) *Cumulative {
+	c := &Cumulative{
+		futures: make([]string, 0, len(v)),
+		weights: make([]int64, len(v)),
+	}
+
+	// Map iteration order is randomised, so the futures need to be sorted for
+	// the selection to be reproducible.
+	for k := range v {
+		c.futures = append(c.futures, k)
+	}
+	sort.Strings(c.futures)
+
+	var total int64
+	for j, k := range c.futures {
+		total += v[k]
+		c.weights[j] = total
+	}
+
+	return c
+}
+
+// Total returns the sum of the number of times each variation was indexed.
+func (c *Cumulative) Total() int64 {
+	if len(c.weights) == 0 {
+		return 0
+	}
+
+	return c.weights[len(c.weights)-1]
+}
+
+// NextFrom returns a random variation selected using r, probability-weighted
+// by the number of times it was indexed. For the same source, it selects the
+// same variation as Variations.NextWeightedRandFrom.
+func (c *Cumulative) NextFrom(r *rand.Rand) string {
+	total := c.Total()
+	if total <= 0 {
+		return ""
+	}
+
+	// Select the first variation whose cumulative weight exceeds a random
+	// number between 0 and the total.
+	n := r.Int63n(total)
+	return c.futures[sort.Search(len(c.weights), func(j int) bool {
+		return c.weights[j] > n
+	})]
+}
@@ -0,0 +1,77 @@
+package stores
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// linearWeightedRand selects a variation by scanning the sorted variations, to
+// check the cumulative weights select the same variations.
+func linearWeightedRand(v Variations, r *rand.Rand) string {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	n := r.Int63n(v.Total())
+	var k string
+	for _, k = range keys {
+		n -= v[k]
+		if n < 0 {
+			break
+		}
+	}
+
+	return k
+}
+
+func TestCumulative(t *testing.T) {
+	c := NewCumulative(Variations{})
+	require.Equal(t, int64(0), c.Total())
+	require.Equal(t, "", c.NextFrom(rand.New(rand.NewSource(1))))
+
+	v := Variations{"or": 2, "to": 3, "be": 5, "not": 0, "a": 1}
+	c = NewCumulative(v)
+	require.Equal(t, int64(11), c.Total())
+	require.Equal(t, []string{"a", "be", "not", "or", "to"}, c.futures)
+	require.Equal(t, []int64{1, 6, 6, 8, 11}, c.weights)
+
+	r1 := rand.New(rand.NewSource(7))
+	r2 := rand.New(rand.NewSource(7))
+	for j := 0; j < 1000; j++ {
+		k := c.NextFrom(r1)
+		require.NotEqual(t, "not", k)
+		require.Equal(t, linearWeightedRand(v, r2), k)
+	}
+}
+
+func TestMemoryCumulative(t *testing.T) {
+	m := NewMemoryStore().(*MemoryStore)
+	ok, c := m.Cumulative("to be")
+	require.False(t, ok)
+	require.Nil(t, c)
+
+	m.Add("to be", "or")
+	ok, c = m.Cumulative("to be")
+	require.True(t, ok)
+	require.Equal(t, int64(1), c.Total())
+
+	// The weights are cached until the key changes.
+	_, c2 := m.Cumulative("to be")
+	require.True(t, c == c2)
+
+	m.Add("to be", "that")
+	_, c2 = m.Cumulative("to be")
+	require.False(t, c == c2)
+	require.Equal(t, int64(2), c2.Total())
+
+	m.Delete("to be")
+	ok, _ = m.Cumulative("to be")
+	require.False(t, ok)
+
+	var _ CumulativeStore = m
+}
@@ -0,0 +1,85 @@
+package stores
+
+// fenwick is a Fenwick (binary indexed) tree of weights. A weight can be
+// appended, changed or removed from the end, and the position holding any
+// cumulative weight can be found, all in logarithmic time.
+type fenwick struct {
+
+	// tree contains the partial sums of the weights. It is 1-indexed, so the
+	// first element is unused.
+	tree []int64
+}
+
+// len returns the number of weights in the tree.
+func (f *fenwick) len() int {
+	if len(f.tree) == 0 {
+		return 0
+	}
+
+	return len(f.tree) - 1
+}
+
+// prefix returns the sum of the first j weights.
+func (f *fenwick) prefix(j int) (sum int64) {
+	for ; j > 0; j -= j & -j {
+		sum += f.tree[j]
+	}
+
+	return
+}
+
+// total returns the sum of all the weights.
+func (f *fenwick) total() int64 {
+	return f.prefix(f.len())
+}
+
+// weight returns the weight at position j.
+func (f *fenwick) weight(j int) int64 {
+	return f.prefix(j+1) - f.prefix(j)
+}
+
+// append adds a weight to the end of the tree.
+func (f *fenwick) append(w int64) {
+	if len(f.tree) == 0 {
+		f.tree = append(f.tree, 0)
+	}
+
+	// The new node holds the sum of the weights it covers, which are the new
+	// weight and the weights already in the tree that precede it.
+	j := len(f.tree)
+	f.tree = append(f.tree, w+f.prefix(j-1)-f.prefix(j-(j&-j)))
+}
+
+// add adds d to the weight at position j.
+func (f *fenwick) add(j int, d int64) {
+	for j++; j < len(f.tree); j += j & -j {
+		f.tree[j] += d
+	}
+}
+
+// truncate removes the last weight from the tree. No other nodes include the
+// last weight, so nothing else needs updating.
+func (f *fenwick) truncate() {
+	if f.len() > 0 {
+		f.tree = f.tree[:len(f.tree)-1]
+	}
+}
+
+// find returns the position of the weight containing the cumulative weight n,
+// which must be at least 0 and less than the total.
+func (f *fenwick) find(n int64) int {
+	step := 1
+	for step*2 <= f.len() {
+		step *= 2
+	}
+
+	var j int
+	for ; step > 0; step /= 2 {
+		if j+step <= f.len() && f.tree[j+step] <= n {
+			j += step
+			n -= f.tree[j]
+		}
+	}
+
+	return j
+}
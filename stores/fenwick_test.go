@@ -0,0 +1,55 @@
+package stores
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFenwick(t *testing.T) {
+	var f fenwick
+	require.Equal(t, 0, f.len())
+	require.Equal(t, int64(0), f.total())
+
+	// Compare against a plain slice of weights through random changes.
+	r := rand.New(rand.NewSource(1))
+	var weights []int64
+	for j := 0; j < 1000; j++ {
+		switch op := r.Intn(3); {
+		case op == 0 || len(weights) == 0:
+			w := r.Int63n(10)
+			f.append(w)
+			weights = append(weights, w)
+		case op == 1:
+			k := r.Intn(len(weights))
+			d := r.Int63n(5)
+			f.add(k, d)
+			weights[k] += d
+		default:
+			f.truncate()
+			weights = weights[:len(weights)-1]
+		}
+
+		require.Equal(t, len(weights), f.len())
+		var sum int64
+		for k, w := range weights {
+			require.Equal(t, sum, f.prefix(k))
+			require.Equal(t, w, f.weight(k))
+			sum += w
+		}
+		require.Equal(t, sum, f.total())
+	}
+}
+
+func TestFenwickFind(t *testing.T) {
+	var f fenwick
+	for _, w := range []int64{2, 0, 3, 1} {
+		f.append(w)
+	}
+
+	expected := []int{0, 0, 2, 2, 2, 3}
+	for n, j := range expected {
+		require.Equal(t, j, f.find(int64(n)), n)
+	}
+}
@@ -0,0 +1,528 @@
+package stores
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+
+	// fileMagic identifies a file store log.
+	fileMagic = "NGRAMLOG"
+
+	// fileVersion is the version of the file store log format.
+	fileVersion byte = 1
+
+	// opAdd is the log operation which adds a number of variations to a key.
+	opAdd byte = 'a'
+
+	// opDelete is the log operation which deletes a key.
+	opDelete byte = 'd'
+
+	// opRemove is the log operation which removes a number of variations from
+	// a key.
+	opRemove byte = 'r'
+)
+
+var (
+	// ErrInvalidFile indicates that a file is not a file store log, or was
+	// written by an unsupported version.
+	ErrInvalidFile = errors.New("invalid store file")
+
+	// errIncompleteRecord indicates that the log ended part way through a
+	// record, which happens if the store crashed part way through a write.
+	errIncompleteRecord = errors.New("incomplete record")
+
+	// errCorruptRecord indicates that a log record failed its checksum or
+	// could not be decoded.
+	errCorruptRecord = errors.New("corrupt record")
+)
+
+// NewFileStore returns an ngram store which is persisted to the file at path,
+// creating the file if it doesn't exist. Every change is appended to the file
+// as a log, which is replayed into memory when the store is opened, so ngrams
+// survive restarts. Records are buffered, and only written to the file at the
+// end of each AddBatch, or by Sync and Close, so if the process is killed the
+// changes made since then are lost; Sync and Close also commit the file to
+// disk, so nothing before them is lost if the system crashes. Any incomplete
+// record at the end of the file, left by a crash, is discarded. If any other
+// record is corrupt, ErrInvalidFile is returned and the file is left as it is.
+func NewFileStore(path string) (Store, error) {
+	s := &FileStore{
+		path:   path,
+		memory: NewMemoryStore().(*MemoryStore),
+	}
+
+	err := s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// FileStore is an ngram store which keeps ngrams in memory and persists them
+// to an append-only log file. It complies with Store interface.
+type FileStore struct {
+
+	// mu serializes writes to the log.
+	mu sync.Mutex
+
+	// path is the path of the log file.
+	path string
+
+	// file is the open log file.
+	file *os.File
+
+	// w buffers writes to the log file.
+	w *bufio.Writer
+
+	// memory contains the indexed grams.
+	memory *MemoryStore
+}
+
+// open opens the log file, replaying any existing records into memory.
+func (s *FileStore) open() error {
+	f, err := os.OpenFile(s.path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	offset, err := s.replay(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	// Discard anything after the last complete record, and write a header if
+	// the file is new.
+	err = f.Truncate(offset)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	s.file = f
+	s.w = bufio.NewWriter(f)
+	if offset == 0 {
+		return writeFileHeader(s.w)
+	}
+
+	return nil
+}
+
+// replay reads the records in the log file into memory, returning the offset
+// of the end of the last complete record. A record which fails its checksum
+// is only treated as incomplete if it's the last in the file, as it may have
+// been torn by a crash; anywhere else the file is invalid.
+func (s *FileStore) replay(f *os.File) (offset int64, err error) {
+	r := bufio.NewReader(f)
+	header := make([]byte, len(fileMagic)+1)
+	_, err = io.ReadFull(r, header)
+	if err == io.EOF {
+		return 0, nil
+	}
+
+	if err != nil || string(header[:len(fileMagic)]) != fileMagic || header[len(fileMagic)] != fileVersion {
+		return 0, ErrInvalidFile
+	}
+
+	offset = int64(len(header))
+	for {
+		var n int64
+		var op byte
+		var key, future string
+		var count uint64
+		n, op, key, future, count, err = readRecord(r)
+		if err == io.EOF || err == errIncompleteRecord {
+			return offset, nil
+		}
+
+		if err == errCorruptRecord {
+			if _, perr := r.Peek(1); perr == io.EOF {
+				return offset, nil
+			}
+
+			return 0, ErrInvalidFile
+		}
+
+		if err != nil {
+			return
+		}
+
+		switch op {
+		case opAdd:
+			s.memory.addN(key, future, int64(count))
+		case opDelete:
+			s.memory.Delete(key)
+		case opRemove:
+			s.memory.removeN(key, future, int64(count))
+		default:
+			return 0, ErrInvalidFile
+		}
+
+		offset += n
+	}
+}
+
+// writeFileHeader writes the magic bytes and version which begin a log file.
+func writeFileHeader(w io.Writer) error {
+	_, err := w.Write(append([]byte(fileMagic), fileVersion))
+	return err
+}
+
+// writeRecord writes a single log record. Each record is the length of its
+// payload, a CRC32 checksum of the payload, and the payload itself, which is
+// the operation, key, future and count.
+func writeRecord(w io.Writer, op byte, key, future string, count uint64) error {
+	payload := make([]byte, 0, len(key)+len(future)+1+3*binary.MaxVarintLen64)
+	payload = append(payload, op)
+	payload = appendString(payload, key)
+	payload = appendString(payload, future)
+	payload = appendUvarint(payload, count)
+
+	record := make([]byte, 0, len(payload)+4+binary.MaxVarintLen64)
+	record = appendUvarint(record, uint64(len(payload)))
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], crc32.ChecksumIEEE(payload))
+	record = append(record, sum[:]...)
+	record = append(record, payload...)
+
+	_, err := w.Write(record)
+	return err
+}
+
+// readRecord reads a single log record, returning its length in bytes. If the
+// log ends part way through the record, errIncompleteRecord is returned, and if
+// the record fails its checksum or can't be decoded, errCorruptRecord is.
+func readRecord(r *bufio.Reader) (n int64, op byte, key, future string, count uint64, err error) {
+	size, err := binary.ReadUvarint(r)
+	if err == io.EOF {
+		return
+	}
+
+	if err == io.ErrUnexpectedEOF {
+		err = errIncompleteRecord
+		return
+	}
+
+	if err != nil || size == 0 || size > 1<<30 {
+		err = errCorruptRecord
+		return
+	}
+
+	record := make([]byte, 4+size)
+	_, err = io.ReadFull(r, record)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = errIncompleteRecord
+		return
+	}
+
+	if err != nil {
+		return
+	}
+
+	payload := record[4:]
+	if binary.LittleEndian.Uint32(record[:4]) != crc32.ChecksumIEEE(payload) {
+		err = errCorruptRecord
+		return
+	}
+
+	n = int64(uvarintLen(size)) + int64(len(record))
+	op = payload[0]
+	payload = payload[1:]
+	key, payload, err = readString(payload)
+	if err != nil {
+		return
+	}
+
+	future, payload, err = readString(payload)
+	if err != nil {
+		return
+	}
+
+	count, l := binary.Uvarint(payload)
+	if l <= 0 {
+		err = errCorruptRecord
+	}
+
+	return
+}
+
+// appendUvarint appends a varint-encoded unsigned integer to b.
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+// appendString appends a length-prefixed string to b.
+func appendString(b []byte, s string) []byte {
+	b = appendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// readString reads a length-prefixed string from b, returning the rest of b.
+func readString(b []byte) (string, []byte, error) {
+	l, n := binary.Uvarint(b)
+	if n <= 0 || uint64(len(b)-n) < l {
+		return "", nil, errCorruptRecord
+	}
+
+	return string(b[n : n+int(l)]), b[n+int(l):], nil
+}
+
+// uvarintLen returns the number of bytes needed to varint-encode v.
+func uvarintLen(v uint64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], v)
+}
+
+// Add adds an ngram to the store and appends it to the log.
+func (s *FileStore) Add(key, future string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := writeRecord(s.w, opAdd, key, future, 1)
+	if err != nil {
+		return err
+	}
+
+	s.memory.addN(key, future, 1)
+
+	return nil
+}
+
+// AddN adds an ngram to the store as though it had been added count times,
+// and appends it to the log as a single record. Counts less than 1 are ignored.
+func (s *FileStore) AddN(key, future string, count int64) error {
+	if count < 1 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := writeRecord(s.w, opAdd, key, future, uint64(count))
+	if err != nil {
+		return err
+	}
+
+	s.memory.addN(key, future, count)
+
+	return nil
+}
+
+// AddBatch adds every entry to the store and appends them to the log, taking
+// the lock only once. The log is flushed to the file at the end of the batch,
+// but not committed to disk. Counts less than 1 are ignored.
+func (s *FileStore) AddBatch(entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for j, e := range entries {
+		if e.Count < 1 {
+			continue
+		}
+
+		// The entries which were already logged are kept, so the store
+		// matches what will be replayed.
+		err := writeRecord(s.w, opAdd, e.Key, e.Future, uint64(e.Count))
+		if err != nil {
+			s.memory.AddBatch(entries[:j])
+			return err
+		}
+	}
+
+	err := s.memory.AddBatch(entries)
+	if err != nil {
+		return err
+	}
+
+	return s.w.Flush()
+}
+
+// Remove removes a single count of a variation from the store and appends the
+// removal to the log.
+func (s *FileStore) Remove(key, future string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := writeRecord(s.w, opRemove, key, future, 1)
+	if err != nil {
+		return err
+	}
+
+	s.memory.removeN(key, future, 1)
+
+	return nil
+}
+
+// Get gets an ngram variation from the store.
+func (s *FileStore) Get(key string) (ok bool, v Variations) {
+	return s.memory.Get(key)
+}
+
+// Delete removes an ngram from the store and appends the deletion to the log.
+func (s *FileStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := writeRecord(s.w, opDelete, key, "", 0)
+	if err != nil {
+		return err
+	}
+
+	return s.memory.Delete(key)
+}
+
+// Any returns a random ngram from the store.
+func (s *FileStore) Any() (string, Variations, error) {
+	return s.memory.Any()
+}
+
+// AnyFrom returns a random ngram from the store, selected uniformly using r.
+func (s *FileStore) AnyFrom(r *rand.Rand) (string, Variations, error) {
+	return s.memory.AnyFrom(r)
+}
+
+// Each calls fn for every key in the store and its variations, stopping at the
+// first error. fn must not add to or delete from the store.
+func (s *FileStore) Each(fn func(key string, v Variations) error) error {
+	return s.memory.Each(fn)
+}
+
+// AnyWeightedFrom returns a random ngram from the store, selected using r with
+// a probability proportional to the number of variations indexed for it.
+func (s *FileStore) AnyWeightedFrom(r *rand.Rand) (string, Variations, error) {
+	return s.memory.AnyWeightedFrom(r)
+}
+
+// Cumulative returns the cumulative weights of the variations of a key. The
+// weights are cached until the key is changed.
+func (s *FileStore) Cumulative(key string) (bool, *Cumulative) {
+	return s.memory.Cumulative(key)
+}
+
+// Len returns the number of keys in the store.
+func (s *FileStore) Len() int {
+	return s.memory.Len()
+}
+
+// ScanPrefix calls fn for every key beginning with prefix and its variations,
+// in key order, stopping at the first error. fn must not add to or delete from
+// the store.
+func (s *FileStore) ScanPrefix(prefix string, fn func(key string, v Variations) error) error {
+	return s.memory.ScanPrefix(prefix, fn)
+}
+
+// Sync flushes any buffered records and commits the log file to disk.
+func (s *FileStore) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sync()
+}
+
+// sync flushes and commits the log file. The caller must hold mu.
+func (s *FileStore) sync() error {
+	err := s.w.Flush()
+	if err != nil {
+		return err
+	}
+
+	return s.file.Sync()
+}
+
+// Compact rewrites the log file so it contains a single record for each
+// variation in the store, rather than every change that was ever made.
+func (s *FileStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.w.Flush()
+	if err != nil {
+		return err
+	}
+
+	// Write the compacted log to a temporary file alongside the original, so
+	// that the original is untouched if anything goes wrong.
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".compact")
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(tmp)
+	err = tmp.Chmod(0644)
+	if err == nil {
+		err = writeFileHeader(w)
+	}
+	if err == nil {
+		err = s.memory.Each(func(key string, v Variations) error {
+			for future, n := range v {
+				err := writeRecord(w, opAdd, key, future, uint64(n))
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	}
+
+	if err == nil {
+		err = w.Flush()
+	}
+
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	// The temporary file is now the log, so appending continues through its
+	// handle, which is already at the end of the file, and the original is
+	// only closed once it's been replaced.
+	s.file.Close()
+	s.file = tmp
+	s.w = bufio.NewWriter(tmp)
+
+	return nil
+}
+
+// Close flushes any buffered records, commits the log file to disk and
+// closes it.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.sync()
+	if err != nil {
+		s.file.Close()
+		return err
+	}
+
+	return s.file.Close()
+}
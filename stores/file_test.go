@@ -0,0 +1,320 @@
+package stores
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tempStorePath returns a path for a file store in a new temporary directory,
+// and a function which removes the directory.
+func tempStorePath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "ngrams")
+	require.NoError(t, err)
+
+	return filepath.Join(dir, "store.log"), func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestNewFileStore(t *testing.T) {
+	path, cleanup := tempStorePath(t)
+	defer cleanup()
+
+	s, err := NewFileStore(path)
+	require.NoError(t, err)
+	require.NotNil(t, s)
+	require.NoError(t, s.Close())
+
+	// A file which isn't a store log can't be opened.
+	err = ioutil.WriteFile(path, []byte("not a store log"), 0644)
+	require.NoError(t, err)
+	_, err = NewFileStore(path)
+	require.Equal(t, ErrInvalidFile, err)
+
+	_, err = NewFileStore(filepath.Join(path, "missing", "store.log"))
+	require.Error(t, err)
+}
+
+func TestFileStorePersists(t *testing.T) {
+	path, cleanup := tempStorePath(t)
+	defer cleanup()
+
+	s, err := NewFileStore(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Add("to be", "or"))
+	require.NoError(t, s.Add("to be", "or"))
+	require.NoError(t, s.Add("to be", "that"))
+	require.NoError(t, s.Add("be or", "not"))
+	require.NoError(t, s.Add("or not", "to"))
+	require.NoError(t, s.Delete("be or"))
+	require.NoError(t, s.Close())
+
+	s, err = NewFileStore(path)
+	require.NoError(t, err)
+	defer s.Close()
+
+	ok, v := s.Get("to be")
+	require.True(t, ok)
+	require.Equal(t, Variations{"or": 2, "that": 1}, v)
+
+	ok, _ = s.Get("be or")
+	require.False(t, ok)
+
+	k, _, err := s.(Sampler).AnyFrom(rand.New(rand.NewSource(1)))
+	require.NoError(t, err)
+	require.Contains(t, []string{"to be", "or not"}, k)
+
+	k, _, err = s.Any()
+	require.NoError(t, err)
+	require.NotEmpty(t, k)
+}
+
+func TestFileStoreRemove(t *testing.T) {
+	path, cleanup := tempStorePath(t)
+	defer cleanup()
+
+	s, err := NewFileStore(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Add("to be", "or"))
+	require.NoError(t, s.Add("to be", "or"))
+	require.NoError(t, s.Add("to be", "that"))
+	require.NoError(t, s.Add("be or", "not"))
+	require.NoError(t, s.(Remover).Remove("to be", "or"))
+	require.NoError(t, s.(Remover).Remove("to be", "that"))
+	require.NoError(t, s.(Remover).Remove("be or", "not"))
+	require.NoError(t, s.Close())
+
+	// Removals are replayed from the log.
+	s, err = NewFileStore(path)
+	require.NoError(t, err)
+	defer s.Close()
+
+	ok, v := s.Get("to be")
+	require.True(t, ok)
+	require.Equal(t, Variations{"or": 1}, v)
+
+	ok, _ = s.Get("be or")
+	require.False(t, ok)
+	require.Equal(t, 1, s.(Sizer).Len())
+}
+
+func TestFileStoreTornWrite(t *testing.T) {
+	path, cleanup := tempStorePath(t)
+	defer cleanup()
+
+	s, err := NewFileStore(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Add("to be", "or"))
+	require.NoError(t, s.Add("be or", "not"))
+	require.NoError(t, s.Close())
+
+	// Simulate a crash part way through writing the last record.
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-2))
+
+	s, err = NewFileStore(path)
+	require.NoError(t, err)
+
+	ok, v := s.Get("to be")
+	require.True(t, ok)
+	require.Equal(t, Variations{"or": 1}, v)
+	ok, _ = s.Get("be or")
+	require.False(t, ok)
+
+	// New records are appended after the last complete record.
+	require.NoError(t, s.Add("or not", "to"))
+	require.NoError(t, s.Close())
+
+	s, err = NewFileStore(path)
+	require.NoError(t, err)
+	defer s.Close()
+	ok, v = s.Get("or not")
+	require.True(t, ok)
+	require.Equal(t, Variations{"to": 1}, v)
+}
+
+func TestFileStoreCorruptRecord(t *testing.T) {
+	path, cleanup := tempStorePath(t)
+	defer cleanup()
+
+	s, err := NewFileStore(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Add("to be", "or"))
+	require.NoError(t, s.Add("be or", "not"))
+	require.NoError(t, s.Add("or not", "to"))
+	require.NoError(t, s.Add("not to", "be"))
+	require.NoError(t, s.Close())
+
+	// Flip the last byte of the second record, which is the end of its count.
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	var records bytes.Buffer
+	require.NoError(t, writeRecord(&records, opAdd, "to be", "or", 1))
+	require.NoError(t, writeRecord(&records, opAdd, "be or", "not", 1))
+	corrupt := append([]byte(nil), data...)
+	corrupt[len(fileMagic)+records.Len()] ^= 0xff
+	require.NoError(t, ioutil.WriteFile(path, corrupt, 0644))
+
+	// Corruption before the end of the log is an error, and the file is kept.
+	_, err = NewFileStore(path)
+	require.Equal(t, ErrInvalidFile, err)
+	after, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, corrupt, after)
+
+	// A corrupt last record may have been torn by a crash, so it's dropped.
+	corrupt = append([]byte(nil), data...)
+	corrupt[len(corrupt)-1] ^= 0xff
+	require.NoError(t, ioutil.WriteFile(path, corrupt, 0644))
+
+	s, err = NewFileStore(path)
+	require.NoError(t, err)
+	require.Equal(t, 3, s.(Sizer).Len())
+	require.NoError(t, s.Close())
+}
+
+func TestFileStoreUnknownOp(t *testing.T) {
+	path, cleanup := tempStorePath(t)
+	defer cleanup()
+
+	var b bytes.Buffer
+	require.NoError(t, writeFileHeader(&b))
+	require.NoError(t, writeRecord(&b, 'x', "to be", "or", 1))
+	require.NoError(t, writeRecord(&b, opAdd, "to be", "or", 1))
+	require.NoError(t, ioutil.WriteFile(path, b.Bytes(), 0644))
+
+	_, err := NewFileStore(path)
+	require.Equal(t, ErrInvalidFile, err)
+	after, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, b.Bytes(), after)
+}
+
+func TestFileStoreCompact(t *testing.T) {
+	path, cleanup := tempStorePath(t)
+	defer cleanup()
+
+	s, err := NewFileStore(path)
+	require.NoError(t, err)
+	for j := 0; j < 100; j++ {
+		require.NoError(t, s.Add("to be", "or"))
+	}
+	require.NoError(t, s.Add("be or", "not"))
+	require.NoError(t, s.Delete("be or"))
+	require.NoError(t, s.(*FileStore).Sync())
+
+	before, err := os.Stat(path)
+	require.NoError(t, err)
+
+	require.NoError(t, s.(*FileStore).Compact())
+	after, err := os.Stat(path)
+	require.NoError(t, err)
+	require.True(t, after.Size() < before.Size())
+	require.Equal(t, os.FileMode(0644), after.Mode().Perm())
+
+	// The compacted log replaces the original, leaving nothing else behind.
+	files, err := ioutil.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(files))
+
+	// The store can still be written to after compacting.
+	require.NoError(t, s.Add("to be", "that"))
+	require.NoError(t, s.Close())
+
+	s, err = NewFileStore(path)
+	require.NoError(t, err)
+	defer s.Close()
+
+	ok, v := s.Get("to be")
+	require.True(t, ok)
+	require.Equal(t, Variations{"or": 100, "that": 1}, v)
+	ok, _ = s.Get("be or")
+	require.False(t, ok)
+}
+
+func TestFileStoreScan(t *testing.T) {
+	path, cleanup := tempStorePath(t)
+	defer cleanup()
+
+	s, err := NewFileStore(path)
+	require.NoError(t, err)
+	defer s.Close()
+
+	s.Add("to be", "or")
+	s.Add("be or", "not")
+	require.Equal(t, 2, s.(Sizer).Len())
+
+	var keys []string
+	err = s.(PrefixScanner).ScanPrefix("to", func(key string, v Variations) error {
+		keys = append(keys, key)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"to be"}, keys)
+
+	n := 0
+	err = s.(Iterator).Each(func(key string, v Variations) error {
+		n++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+}
+
+func TestFileStoreAddN(t *testing.T) {
+	path, cleanup := tempStorePath(t)
+	defer cleanup()
+
+	s, err := NewFileStore(path)
+	require.NoError(t, err)
+	require.NoError(t, s.(CountAdder).AddN("to be", "or", 4))
+	require.NoError(t, s.(CountAdder).AddN("to be", "that", 0))
+	require.NoError(t, s.Add("to be", "or"))
+	require.NoError(t, s.Close())
+
+	// Counts are replayed from the log.
+	s, err = NewFileStore(path)
+	require.NoError(t, err)
+	defer s.Close()
+
+	_, v := s.Get("to be")
+	require.Equal(t, Variations{"or": 5}, v)
+}
+
+func TestFileStoreAddBatch(t *testing.T) {
+	path, cleanup := tempStorePath(t)
+	defer cleanup()
+
+	s, err := NewFileStore(path)
+	require.NoError(t, err)
+	require.NoError(t, s.(Batcher).AddBatch([]Entry{
+		{Key: "to be", Future: "or", Count: 2},
+		{Key: "be or", Future: "not", Count: 1},
+		{Key: "to be", Future: "that", Count: 0},
+	}))
+
+	// Each batch is written to the file without waiting for Sync or Close.
+	r, err := NewFileStore(path)
+	require.NoError(t, err)
+	_, v := r.Get("to be")
+	require.Equal(t, Variations{"or": 2}, v)
+	require.NoError(t, r.Close())
+	require.NoError(t, s.Close())
+
+	// Batches are replayed from the log.
+	s, err = NewFileStore(path)
+	require.NoError(t, err)
+	defer s.Close()
+
+	_, v = s.Get("to be")
+	require.Equal(t, Variations{"or": 2}, v)
+	_, v = s.Get("be or")
+	require.Equal(t, Variations{"not": 1}, v)
+}
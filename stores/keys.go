@@ -0,0 +1,100 @@
+package stores
+
+import (
+	"strings"
+)
+
+const (
+
+	// KeySeparator separates the tokens of a key.
+	KeySeparator = " "
+
+	// keyEscape begins an escape sequence within a token of a key.
+	keyEscape = '\\'
+)
+
+// keyEscaper escapes the characters of a token which would otherwise make a
+// key ambiguous. The record separator is reserved by the index to prefix
+// special keys, so it's escaped too.
+var keyEscaper = strings.NewReplacer(`\`, `\\`, " ", `\s`, "\x1e", `\c`)
+
+// JoinKey returns the store key for a sequence of tokens. The tokens are
+// separated by spaces, and any spaces, backslashes or record separators within
+// a token are escaped, so that different sequences of tokens always have
+// different keys (eg. ["a b", "c"] and ["a", "b c"]). An empty token is
+// written as `\e`. Keys of tokens without those characters are the same as
+// joining the tokens with spaces.
+func JoinKey(tokens []string) string {
+	if len(tokens) == 1 {
+		return escapeToken(tokens[0])
+	}
+
+	escaped := make([]string, len(tokens))
+	for j, t := range tokens {
+		escaped[j] = escapeToken(t)
+	}
+
+	return strings.Join(escaped, KeySeparator)
+}
+
+// SplitKey returns the sequence of tokens of a store key created by JoinKey.
+// The empty key has no tokens.
+func SplitKey(key string) []string {
+	if key == "" {
+		return nil
+	}
+
+	tokens := strings.Split(key, KeySeparator)
+	for j, t := range tokens {
+		tokens[j] = unescapeToken(t)
+	}
+
+	return tokens
+}
+
+// escapeToken escapes a single token of a key.
+func escapeToken(token string) string {
+	if token == "" {
+		return `\e`
+	}
+
+	if !strings.ContainsAny(token, "\\ \x1e") {
+		return token
+	}
+
+	return keyEscaper.Replace(token)
+}
+
+// unescapeToken reverses escapeToken. Unknown escape sequences are kept as-is.
+func unescapeToken(token string) string {
+	if token == `\e` {
+		return ""
+	}
+
+	if strings.IndexByte(token, keyEscape) == -1 {
+		return token
+	}
+
+	var b strings.Builder
+	for j := 0; j < len(token); j++ {
+		if token[j] != keyEscape || j+1 == len(token) {
+			b.WriteByte(token[j])
+			continue
+		}
+
+		j++
+		switch token[j] {
+		case '\\':
+			b.WriteByte('\\')
+		case 's':
+			b.WriteByte(' ')
+		case 'c':
+			b.WriteByte('\x1e')
+		default:
+			b.WriteByte(keyEscape)
+			b.WriteByte(token[j])
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,34 @@
+package stores
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoinKey(t *testing.T) {
+	require.Equal(t, "", JoinKey(nil))
+	require.Equal(t, "to be", JoinKey([]string{"to", "be"}))
+	require.Equal(t, `a\sb c`, JoinKey([]string{"a b", "c"}))
+	require.Equal(t, `a b\sc`, JoinKey([]string{"a", "b c"}))
+	require.Equal(t, `\\s \c \e`, JoinKey([]string{`\s`, "\x1e", ""}))
+}
+
+func TestSplitKey(t *testing.T) {
+	tt := [][]string{
+		nil,
+		{"to", "be"},
+		{"a b", "c"},
+		{"a", "b c"},
+		{`\s`, "\x1e", ""},
+		{`ends\`, "\\\\"},
+		{""},
+	}
+
+	for _, tokens := range tt {
+		require.Equal(t, tokens, SplitKey(JoinKey(tokens)))
+	}
+
+	// Unknown escapes are kept as-is.
+	require.Equal(t, []string{`\x`, `y\`}, SplitKey(`\x y\`))
+}
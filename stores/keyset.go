@@ -0,0 +1,95 @@
+package stores
+
+import (
+	"math/rand"
+)
+
+// keySet contains the keys of a store in the order they were added, along with
+// a weight for each key, so that a random key can be selected uniformly in
+// constant time or by weight in logarithmic time. It is not safe for
+// concurrent use, so must be guarded by the store's lock. Stores which can hold
+// the position of each key alongside its variations can use appendKey and
+// removeAt directly, leaving positions empty to save memory.
+type keySet struct {
+
+	// keys contains each key, in the order they were added.
+	keys []string
+
+	// positions contains the index of each key in keys, if added by addKey.
+	positions map[string]int
+
+	// weights contains the weight of each key in keys, which is the total
+	// number of times its variations were indexed.
+	weights fenwick
+}
+
+// addKey adds a new key with an initial weight.
+func (k *keySet) addKey(key string, weight int64) {
+	if k.positions == nil {
+		k.positions = make(map[string]int)
+	}
+
+	k.positions[key] = k.appendKey(key, weight)
+}
+
+// appendKey adds a new key with an initial weight, returning its position.
+func (k *keySet) appendKey(key string, weight int64) int {
+	k.keys = append(k.keys, key)
+	k.weights.append(weight)
+
+	return len(k.keys) - 1
+}
+
+// addWeight adds n to the weight of a key.
+func (k *keySet) addWeight(key string, n int64) {
+	if j, ok := k.positions[key]; ok {
+		k.weights.add(j, n)
+	}
+}
+
+// removeKey removes a key by swapping the last key into its position.
+func (k *keySet) removeKey(key string) {
+	j, ok := k.positions[key]
+	if !ok {
+		return
+	}
+
+	delete(k.positions, key)
+	if moved, ok := k.removeAt(j); ok {
+		k.positions[moved] = j
+	}
+}
+
+// removeAt removes the key at position j by swapping the last key into its
+// position, returning the key which was moved, or false if j was the last.
+func (k *keySet) removeAt(j int) (string, bool) {
+	last := len(k.keys) - 1
+	moved := k.keys[last]
+	k.keys[j] = moved
+	k.keys = k.keys[:last]
+
+	k.weights.add(j, k.weights.weight(last)-k.weights.weight(j))
+	k.weights.truncate()
+
+	return moved, j != last
+}
+
+// uniform returns a key selected uniformly using r, or false if there are none.
+func (k *keySet) uniform(r *rand.Rand) (string, bool) {
+	if len(k.keys) == 0 {
+		return "", false
+	}
+
+	return k.keys[r.Intn(len(k.keys))], true
+}
+
+// weighted returns a key selected using r with a probability proportional to
+// its weight, or false if there are none.
+func (k *keySet) weighted(r *rand.Rand) (string, bool) {
+	total := k.weights.total()
+	if total <= 0 {
+		return "", false
+	}
+
+	return k.keys[k.weights.find(r.Int63n(total))], true
+}
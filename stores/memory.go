@@ -1,6 +1,9 @@
 package stores
 
 import (
+	"math/rand"
+	"sort"
+	"strings"
 	"sync"
 )
 
@@ -20,28 +23,124 @@ type MemoryStore struct {
 
 	// internal contains the indexed grams.
 	internal Grams
+
+	// keySet contains each key in internal, so a random key can be selected
+	// reproducibly and quickly.
+	keySet
+
+	// cumulative caches the cumulative weights of the variations of keys which
+	// have been sampled, until the key is next changed.
+	cumulative map[string]*Cumulative
 }
 
 // Add adds an ngram to the store.
 func (s *MemoryStore) Add(key, future string) error {
+	s.addN(key, future, 1)
+	return nil
+}
+
+// AddN adds an ngram to the store as though it had been added count times.
+// Counts less than 1 are ignored.
+func (s *MemoryStore) AddN(key, future string, count int64) error {
+	if count > 0 {
+		s.addN(key, future, count)
+	}
+
+	return nil
+}
+
+// AddBatch adds every entry to the store, taking the lock only once. Counts less
+// than 1 are ignored.
+func (s *MemoryStore) AddBatch(entries []Entry) error {
 	s.Lock()
 	defer s.Unlock()
 
+	for _, e := range entries {
+		if e.Count > 0 {
+			s.add(e.Key, e.Future, e.Count)
+		}
+	}
+
+	return nil
+}
+
+// addN adds an ngram to the store as though it had been added n times.
+func (s *MemoryStore) addN(key, future string, n int64) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.add(key, future, n)
+}
+
+// add adds an ngram to the store as though it had been added n times. The
+// caller must hold the lock.
+func (s *MemoryStore) add(key, future string, n int64) {
+	delete(s.cumulative, key)
+
 	// If this particular key doesn't exist at all, we can add it with
-	// the provided future, and a starting quantity of 1.
+	// the provided future, and a starting quantity of n.
 	if _, ok := s.internal[key]; !ok {
 		s.internal[key] = Variations{
-			future: 1,
+			future: n,
 		}
-		return nil
+		s.addKey(key, n)
+		return
 	}
 
 	// If the gram _does_ exist, then we need to add the variation if it
 	// doesn't exist, and then ensure the variation quantity is incremented.
-	if _, ok := s.internal[key][future]; !ok {
-		s.internal[key][future] = 0
+	s.internal[key][future] += n
+	s.addWeight(key, n)
+}
+
+// Remove removes a single count of a variation from the store, removing the
+// variation when it reaches zero, and the key when it has no variations left.
+func (s *MemoryStore) Remove(key, future string) error {
+	s.removeN(key, future, 1)
+	return nil
+}
+
+// removeN removes up to n counts of a variation from the store.
+func (s *MemoryStore) removeN(key, future string, n int64) {
+	s.Lock()
+	defer s.Unlock()
+
+	v, ok := s.internal[key]
+	if !ok || v[future] <= 0 {
+		return
+	}
+
+	delete(s.cumulative, key)
+
+	if n >= v[future] {
+		n = v[future]
+		delete(v, future)
+	} else {
+		v[future] -= n
+	}
+
+	if len(v) == 0 {
+		delete(s.internal, key)
+		s.removeKey(key)
+		return
+	}
+
+	s.addWeight(key, -n)
+}
+
+// Each calls fn for every key in the store and its variations, stopping at the
+// first error. The store is locked for reading while iterating, so fn must not
+// add to or delete from it.
+func (s *MemoryStore) Each(fn func(key string, v Variations) error) error {
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, k := range s.keys {
+		err := fn(k, s.internal[k])
+		if err != nil {
+			return err
+		}
 	}
-	s.internal[key][future]++
 
 	return nil
 }
@@ -62,24 +161,100 @@ func (s *MemoryStore) Delete(key string) error {
 	defer s.Unlock()
 
 	delete(s.internal, key)
+	delete(s.cumulative, key)
+	s.removeKey(key)
 
 	return nil
 }
 
-// Any returns a random ngram from the store.
+// Cumulative returns the cumulative weights of the variations of a key. The
+// weights are cached until the key is changed.
+func (s *MemoryStore) Cumulative(key string) (bool, *Cumulative) {
+	s.RLock()
+	c, ok := s.cumulative[key]
+	s.RUnlock()
+	if ok {
+		return true, c
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	v, ok := s.internal[key]
+	if !ok {
+		return false, nil
+	}
+
+	if s.cumulative == nil {
+		s.cumulative = make(map[string]*Cumulative)
+	}
+
+	c = NewCumulative(v)
+	s.cumulative[key] = c
+
+	return true, c
+}
+
+// Len returns the number of keys in the store.
+func (s *MemoryStore) Len() int {
+	s.RLock()
+	defer s.RUnlock()
+
+	return len(s.keys)
+}
+
+// ScanPrefix calls fn for every key beginning with prefix and its variations,
+// in key order, stopping at the first error. The store is locked for reading
+// while scanning, so fn must not add to or delete from it.
+func (s *MemoryStore) ScanPrefix(prefix string, fn func(key string, v Variations) error) error {
+	s.RLock()
+	defer s.RUnlock()
+
+	var keys []string
+	for _, k := range s.keys {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		err := fn(k, s.internal[k])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Any returns a random ngram from the store, selected uniformly.
 func (s *MemoryStore) Any() (k string, v Variations, err error) {
+	return s.AnyFrom(defaultRand)
+}
+
+// AnyFrom returns a random ngram from the store, selected uniformly using r.
+func (s *MemoryStore) AnyFrom(r *rand.Rand) (k string, v Variations, err error) {
 	s.RLock()
 	defer s.RUnlock()
 
-	// Maps are unordered, so far these purposes we can just take
-	// whatever is the first in the range. In a more sensitive
-	// environment it would be better to use a randomizer.
-	for k, v = range s.internal {
+	k, ok := s.uniform(r)
+	if ok {
+		v = s.internal[k]
+	}
+
+	return
+}
+
+// AnyWeightedFrom returns a random ngram from the store, selected using r with
+// a probability proportional to the number of variations indexed for it.
+func (s *MemoryStore) AnyWeightedFrom(r *rand.Rand) (k string, v Variations, err error) {
+	s.RLock()
+	defer s.RUnlock()
 
-		// If you wanted to get a bit clever here, you could implement
-		// some kind of check that only selected starter ngrams, but this
-		// would require storing metadata with the ngram.
-		break
+	k, ok := s.weighted(r)
+	if ok {
+		v = s.internal[k]
 	}
 
 	return
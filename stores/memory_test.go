@@ -1,6 +1,8 @@
 package stores
 
 import (
+	"errors"
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -70,6 +72,46 @@ func TestMemoryAny(t *testing.T) {
 	require.Equal(t, true, k == "to be" || k == "be or")
 }
 
+func TestMemoryAnyFrom(t *testing.T) {
+	m := NewMemoryStore().(*MemoryStore)
+	k, v, err := m.AnyFrom(rand.New(rand.NewSource(1)))
+	require.NoError(t, err)
+	require.Empty(t, k)
+	require.Nil(t, v)
+
+	keys := []string{"to be", "be or", "or not", "not to"}
+	for _, key := range keys {
+		m.Add(key, "future")
+	}
+	require.Equal(t, keys, m.keys)
+
+	var a, b []string
+	r1 := rand.New(rand.NewSource(42))
+	r2 := rand.New(rand.NewSource(42))
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		k, v, err := m.AnyFrom(r1)
+		require.NoError(t, err)
+		require.Equal(t, Variations{"future": 1}, v)
+		a = append(a, k)
+		seen[k] = true
+
+		k, _, _ = m.AnyFrom(r2)
+		b = append(b, k)
+	}
+	require.Equal(t, a, b)
+	require.Equal(t, 4, len(seen))
+
+	// Deleted keys are removed from the ordered keys.
+	m.Delete("be or")
+	require.Equal(t, []string{"to be", "not to", "or not"}, m.keys)
+	require.Equal(t, 1, m.positions["not to"])
+	m.Delete("missing")
+	require.Equal(t, 3, len(m.keys))
+
+	var _ Sampler = m
+}
+
 func TestMemoryRemove(t *testing.T) {
 	m := &MemoryStore{
 		internal: Grams{
@@ -99,3 +141,167 @@ func TestMemoryClose(t *testing.T) {
 	err := m.Close()
 	require.NoError(t, err)
 }
+
+func TestMemoryEach(t *testing.T) {
+	s := NewMemoryStore()
+	s.Add("to be", "or")
+	s.Add("to be", "that")
+	s.Add("be or", "not")
+
+	got := make(Grams)
+	err := s.(Iterator).Each(func(key string, v Variations) error {
+		got[key] = v
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, Grams{
+		"to be": {"or": 1, "that": 1},
+		"be or": {"not": 1},
+	}, got)
+
+	err = s.(Iterator).Each(func(key string, v Variations) error {
+		return errors.New("test")
+	})
+	require.Error(t, err)
+}
+
+func TestMemoryLen(t *testing.T) {
+	s := NewMemoryStore()
+	require.Equal(t, 0, s.(Sizer).Len())
+
+	s.Add("to be", "or")
+	s.Add("to be", "that")
+	s.Add("be or", "not")
+	require.Equal(t, 2, s.(Sizer).Len())
+
+	s.Delete("to be")
+	require.Equal(t, 1, s.(Sizer).Len())
+}
+
+func TestMemoryScanPrefix(t *testing.T) {
+	s := NewMemoryStore()
+	s.Add("to bed", "now")
+	s.Add("to be", "or")
+	s.Add("to be", "that")
+	s.Add("be or", "not")
+
+	var keys []string
+	err := s.(PrefixScanner).ScanPrefix("to be", func(key string, v Variations) error {
+		keys = append(keys, key)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"to be", "to bed"}, keys)
+
+	keys = nil
+	err = s.(PrefixScanner).ScanPrefix("", func(key string, v Variations) error {
+		keys = append(keys, key)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"be or", "to be", "to bed"}, keys)
+
+	err = s.(PrefixScanner).ScanPrefix("to", func(key string, v Variations) error {
+		return errors.New("test")
+	})
+	require.Error(t, err)
+}
+
+func TestMemoryAnyWeightedFrom(t *testing.T) {
+	m := NewMemoryStore().(*MemoryStore)
+	k, v, err := m.AnyWeightedFrom(rand.New(rand.NewSource(1)))
+	require.NoError(t, err)
+	require.Empty(t, k)
+	require.Nil(t, v)
+
+	for j := 0; j < 9; j++ {
+		m.Add("to be", "or")
+	}
+	m.Add("be or", "not")
+	m.Add("or not", "to")
+	m.Add("not to", "be")
+
+	// The weights follow keys which are moved when another is deleted.
+	m.Delete("be or")
+	require.Equal(t, int64(9), m.weights.weight(m.positions["to be"]))
+	require.Equal(t, int64(1), m.weights.weight(m.positions["not to"]))
+	require.Equal(t, int64(11), m.weights.total())
+
+	counts := map[string]int{}
+	r := rand.New(rand.NewSource(42))
+	for j := 0; j < 11000; j++ {
+		k, _, err := m.AnyWeightedFrom(r)
+		require.NoError(t, err)
+		counts[k]++
+	}
+
+	require.Equal(t, 0, counts["be or"])
+	require.InDelta(t, 9000, counts["to be"], 300)
+	require.InDelta(t, 1000, counts["or not"], 200)
+	require.InDelta(t, 1000, counts["not to"], 200)
+
+	var _ WeightedSampler = m
+}
+
+func TestMemoryRemoveVariation(t *testing.T) {
+	m := NewMemoryStore().(*MemoryStore)
+	m.Add("to be", "or")
+	m.Add("to be", "or")
+	m.Add("to be", "that")
+	m.Add("be or", "not")
+	m.Cumulative("to be")
+
+	require.NoError(t, m.Remove("to be", "or"))
+	require.Equal(t, Variations{"or": 1, "that": 1}, m.internal["to be"])
+	require.Equal(t, int64(2), m.weights.weight(m.positions["to be"]))
+	require.Nil(t, m.cumulative["to be"])
+
+	require.NoError(t, m.Remove("to be", "that"))
+	require.Equal(t, Variations{"or": 1}, m.internal["to be"])
+
+	// Missing variations and keys are ignored.
+	require.NoError(t, m.Remove("to be", "missing"))
+	require.NoError(t, m.Remove("missing", "or"))
+	require.Equal(t, int64(2), m.weights.total())
+
+	// Keys are removed with their last variation.
+	require.NoError(t, m.Remove("be or", "not"))
+	_, ok := m.internal["be or"]
+	require.False(t, ok)
+	require.Equal(t, []string{"to be"}, m.keys)
+	require.Equal(t, int64(1), m.weights.total())
+
+	var _ Remover = m
+}
+
+func TestMemoryAddN(t *testing.T) {
+	s := NewMemoryStore().(*MemoryStore)
+	require.NoError(t, s.AddN("to be", "or", 5))
+	require.NoError(t, s.AddN("to be", "or", 2))
+	require.NoError(t, s.AddN("to be", "that", 0))
+	require.NoError(t, s.AddN("to be", "that", -1))
+
+	_, v := s.Get("to be")
+	require.Equal(t, Variations{"or": 7}, v)
+	require.Equal(t, int64(7), s.weights.total())
+
+	var _ CountAdder = s
+}
+
+func TestMemoryAddBatch(t *testing.T) {
+	s := NewMemoryStore().(*MemoryStore)
+	require.NoError(t, s.AddBatch([]Entry{
+		{Key: "to be", Future: "or", Count: 2},
+		{Key: "be or", Future: "not", Count: 1},
+		{Key: "to be", Future: "or", Count: 1},
+		{Key: "to be", Future: "that", Count: 0},
+	}))
+
+	_, v := s.Get("to be")
+	require.Equal(t, Variations{"or": 3}, v)
+	_, v = s.Get("be or")
+	require.Equal(t, Variations{"not": 1}, v)
+	require.Equal(t, int64(4), s.weights.total())
+
+	var _ Batcher = s
+}
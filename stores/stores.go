@@ -1,10 +1,18 @@
 package stores
 
 import (
+	"context"
 	"math/rand"
+	"sync"
 	"time"
 )
 
+var (
+	// defaultRand is the source of randomness used when no other source is
+	// given. It is seeded once and is safe for concurrent use.
+	defaultRand = NewLockedRand(time.Now().UnixNano())
+)
+
 // Store is a data storage mechanism for ngrams.
 type Store interface {
 
@@ -15,7 +23,8 @@ type Store interface {
 
 	// Add adds a new ngram and key-variation pair to the index. It
 	// should take the key, which will be used to index the gram, and
-	// the future, which is a slice of consequent gram tokens.
+	// the future, which is a slice of consequent gram tokens. The index
+	// creates keys from tokens with JoinKey.
 	// I like to add labels to my interface method inputs because it
 	// makes the context a little easier to understand for whoever
 	// follows my footsteps (sometimes that's future-me).
@@ -34,6 +43,223 @@ type Store interface {
 	Close() error
 }
 
+// Sampler is an optional interface which can be implemented by a Store that
+// is able to select a random ngram using a given source of randomness, so that
+// the selection is reproducible for a given seed.
+type Sampler interface {
+
+	// AnyFrom returns a random ngram from the store, selected using r.
+	AnyFrom(r *rand.Rand) (string, Variations, error)
+}
+
+// Iterator is an optional interface which can be implemented by a Store that
+// is able to visit every ngram it contains, such as for saving a snapshot.
+type Iterator interface {
+
+	// Each calls fn for every key in the store and its variations, stopping at
+	// the first error.
+	Each(fn func(key string, v Variations) error) error
+}
+
+// WeightedSampler is an optional interface which can be implemented by a Store
+// that is able to select a random ngram weighted by how often it was indexed.
+type WeightedSampler interface {
+
+	// AnyWeightedFrom returns a random ngram from the store, selected using r
+	// with a probability proportional to the total number of times its
+	// variations were indexed.
+	AnyWeightedFrom(r *rand.Rand) (string, Variations, error)
+}
+
+// CumulativeStore is an optional interface which can be implemented by a Store
+// that caches the cumulative weights of the variations of each key, so that a
+// variation can be selected without sorting and summing them every time.
+type CumulativeStore interface {
+
+	// Cumulative returns the cumulative weights of the variations of a key.
+	Cumulative(key string) (bool, *Cumulative)
+}
+
+// Sizer is an optional interface which can be implemented by a Store that is
+// able to count the ngram keys it contains.
+type Sizer interface {
+
+	// Len returns the number of keys in the store.
+	Len() int
+}
+
+// PrefixScanner is an optional interface which can be implemented by a Store
+// that is able to find every key beginning with a prefix.
+type PrefixScanner interface {
+
+	// ScanPrefix calls fn for every key beginning with prefix and its
+	// variations, in key order, stopping at the first error. The prefix is
+	// matched against the key string, so "to be" also matches "to bed".
+	ScanPrefix(prefix string, fn func(key string, v Variations) error) error
+}
+
+// CountAdder is an optional interface which can be implemented by a Store that
+// is able to add many counts of a variation at once, such as when loading
+// pre-counted ngrams. Use the AddN function to add counts to any Store.
+type CountAdder interface {
+
+	// AddN adds an ngram to the store as though it had been added count
+	// times. Counts less than 1 are ignored.
+	AddN(key, future string, count int64) error
+}
+
+// AddN adds an ngram to a store as though it had been added count times,
+// as per CountAdder.AddN. If the store doesn't implement CountAdder, the
+// ngram is added count times.
+func AddN(s Store, key, future string, count int64) error {
+	if ca, ok := s.(CountAdder); ok {
+		return ca.AddN(key, future, count)
+	}
+
+	for j := int64(0); j < count; j++ {
+		err := s.Add(key, future)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Entry is a single ngram to be added to a store with a count, as part of a
+// batch.
+type Entry struct {
+
+	// Key is the key of the ngram, as joined by JoinKey.
+	Key string
+
+	// Future is the variation which followed the key.
+	Future string
+
+	// Count is the number of times the ngram is added.
+	Count int64
+}
+
+// Batcher is an optional interface which can be implemented by a Store that is
+// able to add many ngrams at once, such as by taking a lock or making a
+// request once per batch rather than once per ngram. Use the AddBatch function
+// to add a batch to any Store.
+type Batcher interface {
+
+	// AddBatch adds every entry to the store, as per CountAdder.AddN. The
+	// entries may be reused once it returns, so must not be retained.
+	AddBatch(entries []Entry) error
+}
+
+// AddBatch adds a batch of entries to a store, as per Batcher.AddBatch. If the
+// store doesn't implement Batcher, each entry is added with AddN, or one count
+// at a time with AddContext if the store implements ContextStore. The context
+// is checked for cancellation between entries.
+func AddBatch(ctx context.Context, s Store, entries []Entry) error {
+	if b, ok := s.(Batcher); ok {
+		return b.AddBatch(entries)
+	}
+
+	cs, ok := s.(ContextStore)
+	for _, e := range entries {
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			err = AddN(s, e.Key, e.Future, e.Count)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		for j := int64(0); j < e.Count; j++ {
+			err = cs.AddContext(ctx, e.Key, e.Future)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Remover is an optional interface which can be implemented by a Store that is
+// able to decrement the count of a single variation, such as for retracting
+// training data. Use the Remove function to remove from any Store.
+type Remover interface {
+
+	// Remove decrements the number of times a variation was indexed following
+	// a key, removing the variation when it reaches zero, and the key when it
+	// has no variations left. Removing a variation which was never indexed
+	// does nothing.
+	Remove(key, future string) error
+}
+
+// Remove removes a single count of a variation from a store, as per
+// Remover.Remove. If the store doesn't implement Remover, the key is deleted
+// and its remaining variations are added again.
+func Remove(s Store, key, future string) error {
+	if r, ok := s.(Remover); ok {
+		return r.Remove(key, future)
+	}
+
+	ok, v := s.Get(key)
+	if !ok || v[future] <= 0 {
+		return nil
+	}
+
+	// The variations may belong to the store, so they're copied before the key
+	// is deleted.
+	remaining := make(Variations, len(v))
+	for f, n := range v {
+		remaining[f] = n
+	}
+	remaining[future]--
+
+	err := s.Delete(key)
+	if err != nil {
+		return err
+	}
+
+	for f, n := range remaining {
+		err = AddN(s, key, f, n)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ContextStore is an optional interface which can be implemented by a Store
+// that is able to abandon an addition when a context is cancelled, such as a
+// store backed by a network connection.
+type ContextStore interface {
+
+	// AddContext adds a new ngram and key-variation pair to the index, as per
+	// Add, unless the context is cancelled first.
+	AddContext(ctx context.Context, key, future string) error
+}
+
+// AddContext adds an ngram to a store, passing the context to the store if it
+// implements ContextStore. Otherwise, the context is checked for cancellation
+// before the ngram is added.
+func AddContext(ctx context.Context, s Store, key, future string) error {
+	if cs, ok := s.(ContextStore); ok {
+		return cs.AddContext(ctx, key, future)
+	}
+
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	return s.Add(key, future)
+}
+
 // Grams is a map of Variations keyed on gram-key (eg. "to be").
 // This is primarily used by the in-memory store, but can also be used to
 // structure data for other storage engines.
@@ -45,31 +271,54 @@ type Grams map[string]Variations
 // they were indexed, keyed on gram-variation (eg. {"be or":3})
 type Variations map[string]int64
 
+// Total returns the sum of the number of times each variation was indexed.
+func (v Variations) Total() (total int64) {
+	for _, i := range v {
+		total += i
+	}
+
+	return
+}
+
 // NextWeightedRand returns a random variation, probability-weighted by the
 // number of times it was indexed.
-// Using a linear scan ala https://blog.bruce-hill.com/a-faster-weighted-random-choice
 func (v *Variations) NextWeightedRand() string {
-	rand.Seed(time.Now().UnixNano())
+	return v.NextWeightedRandFrom(defaultRand)
+}
 
-	// Get a sum total of the probabities of all variations.
-	var total int64
-	for _, i := range *v {
-		total += i
-	}
+// NextWeightedRandFrom returns a random variation selected using r, probability-
+// weighted by the number of times it was indexed. The variations are considered
+// in a sorted order, so the same source will always select the same variation.
+// Stores which implement CumulativeStore can cache the cumulative weights to
+// avoid sorting the variations for every selection.
+func (v *Variations) NextWeightedRandFrom(r *rand.Rand) string {
+	return NewCumulative(*v).NextFrom(r)
+}
 
-	// Pick a random int between 0 and the total sum.
-	r := rand.Int63n(total)
-
-	// Range through the possible variations and subtract the probability
-	// weight from the random number. If r goes below zero, select the key.
-	var k string
-	var i int64
-	for k, i = range *v {
-		r -= i
-		if r < 0 {
-			break
-		}
-	}
+// NewLockedRand returns a *rand.Rand seeded with seed which, unlike those
+// returned by rand.New, is safe for concurrent use.
+func NewLockedRand(seed int64) *rand.Rand {
+	return rand.New(&lockedSource{
+		src: rand.NewSource(seed),
+	})
+}
+
+// lockedSource is a rand.Source which is safe for concurrent use.
+type lockedSource struct {
+	sync.Mutex
+	src rand.Source
+}
+
+// Int63 returns a non-negative pseudo-random 63-bit integer.
+func (s *lockedSource) Int63() int64 {
+	s.Lock()
+	defer s.Unlock()
+	return s.src.Int63()
+}
 
-	return k
+// Seed seeds the underlying source.
+func (s *lockedSource) Seed(seed int64) {
+	s.Lock()
+	defer s.Unlock()
+	s.src.Seed(seed)
 }
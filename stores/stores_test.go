@@ -1,6 +1,8 @@
 package stores
 
 import (
+	"context"
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -31,3 +33,156 @@ func TestVariationsNextWeightedRand(t *testing.T) {
 	require.Equal(t, true, (results["be"] > 4800 && results["be"] < 5200))
 
 }
+
+func TestVariationsTotal(t *testing.T) {
+	v := Variations{
+		"or": 2,
+		"to": 3,
+		"be": 5,
+	}
+	require.Equal(t, int64(10), v.Total())
+	require.Equal(t, int64(0), Variations{}.Total())
+}
+
+func TestVariationsNextWeightedRandFrom(t *testing.T) {
+	v := &Variations{
+		"or": 2,
+		"to": 3,
+		"be": 5,
+	}
+
+	var a, b []string
+	r1 := rand.New(rand.NewSource(42))
+	r2 := rand.New(rand.NewSource(42))
+	for i := 0; i < 100; i++ {
+		a = append(a, v.NextWeightedRandFrom(r1))
+		b = append(b, v.NextWeightedRandFrom(r2))
+	}
+	require.Equal(t, a, b)
+
+	require.Equal(t, "", (&Variations{}).NextWeightedRandFrom(r1))
+	require.Equal(t, "", (&Variations{}).NextWeightedRand())
+}
+
+// contextStore is a memory store which records the contexts it was given.
+type contextStore struct {
+	*MemoryStore
+	ctx context.Context
+}
+
+func (s *contextStore) AddContext(ctx context.Context, key, future string) error {
+	s.ctx = ctx
+	return s.Add(key, future)
+}
+
+func TestAddContext(t *testing.T) {
+	s := NewMemoryStore().(*MemoryStore)
+	err := AddContext(context.Background(), s, "to be", "or")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), s.internal["to be"]["or"])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = AddContext(ctx, s, "to be", "or")
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, int64(1), s.internal["to be"]["or"])
+
+	// Stores which accept a context are given it.
+	cs := &contextStore{MemoryStore: NewMemoryStore().(*MemoryStore)}
+	err = AddContext(ctx, cs, "to be", "or")
+	require.NoError(t, err)
+	require.Equal(t, ctx, cs.ctx)
+}
+
+// plainStore hides the optional interfaces of a memory store.
+type plainStore struct {
+	Store
+}
+
+func TestRemove(t *testing.T) {
+	m := NewMemoryStore()
+	s := &plainStore{m}
+	s.Add("to be", "or")
+	s.Add("to be", "or")
+	s.Add("to be", "that")
+
+	// Stores which don't implement Remover are rebuilt.
+	require.NoError(t, Remove(s, "to be", "or"))
+	_, v := s.Get("to be")
+	require.Equal(t, Variations{"or": 1, "that": 1}, v)
+
+	require.NoError(t, Remove(s, "to be", "missing"))
+	require.NoError(t, Remove(s, "missing", "or"))
+	_, v = s.Get("to be")
+	require.Equal(t, Variations{"or": 1, "that": 1}, v)
+
+	require.NoError(t, Remove(s, "to be", "or"))
+	require.NoError(t, Remove(s, "to be", "that"))
+	ok, _ := s.Get("to be")
+	require.False(t, ok)
+
+	// Stores which implement Remover are used directly.
+	m.Add("be or", "not")
+	require.NoError(t, Remove(m, "be or", "not"))
+	ok, _ = m.Get("be or")
+	require.False(t, ok)
+}
+
+func TestAddN(t *testing.T) {
+	m := NewMemoryStore()
+	s := &plainStore{m}
+
+	// Stores which don't implement CountAdder are added to count times.
+	require.NoError(t, AddN(s, "to be", "or", 3))
+	require.NoError(t, AddN(s, "to be", "that", 0))
+	_, v := s.Get("to be")
+	require.Equal(t, Variations{"or": 3}, v)
+
+	// Stores which implement CountAdder are used directly.
+	require.NoError(t, AddN(m, "to be", "that", 2))
+	_, v = m.Get("to be")
+	require.Equal(t, Variations{"or": 3, "that": 2}, v)
+}
+
+// countingContextStore counts the ngrams added with a context, hiding the
+// optional interfaces of the store it wraps.
+type countingContextStore struct {
+	Store
+	added int
+}
+
+func (s *countingContextStore) AddContext(ctx context.Context, key, future string) error {
+	s.added++
+	return AddContext(ctx, s.Store, key, future)
+}
+
+func TestAddBatch(t *testing.T) {
+	entries := []Entry{
+		{Key: "to be", Future: "or", Count: 2},
+		{Key: "be or", Future: "not", Count: 1},
+		{Key: "to be", Future: "that", Count: 0},
+	}
+
+	// Stores which implement Batcher are used directly.
+	m := NewMemoryStore()
+	require.NoError(t, AddBatch(context.Background(), m, entries))
+	_, v := m.Get("to be")
+	require.Equal(t, Variations{"or": 2}, v)
+
+	// Other stores have each entry added.
+	p := &plainStore{NewMemoryStore()}
+	require.NoError(t, AddBatch(context.Background(), p, entries))
+	_, v = p.Get("to be")
+	require.Equal(t, Variations{"or": 2}, v)
+	_, v = p.Get("be or")
+	require.Equal(t, Variations{"not": 1}, v)
+
+	// Context stores have each count added with the context.
+	c := &countingContextStore{Store: NewMemoryStore()}
+	require.NoError(t, AddBatch(context.Background(), c, entries))
+	require.Equal(t, 3, c.added)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, context.Canceled, AddBatch(ctx, p, entries))
+}
@@ -0,0 +1,54 @@
+package stores
+
+// Vocabulary interns tokens, mapping each distinct token to a uint32 ID so that
+// it only needs to be held in memory once. IDs are assigned in the order the
+// tokens are first interned, starting from 0. A Vocabulary is not safe for
+// concurrent use, so must be guarded by the store's lock.
+type Vocabulary struct {
+
+	// ids contains the ID of each token.
+	ids map[string]uint32
+
+	// tokens contains each token, indexed by ID.
+	tokens []string
+}
+
+// NewVocabulary returns an empty vocabulary.
+func NewVocabulary() *Vocabulary {
+	return &Vocabulary{
+		ids: make(map[string]uint32),
+	}
+}
+
+// Intern returns the ID of a token, assigning a new ID if it has not been seen.
+func (v *Vocabulary) Intern(token string) uint32 {
+	if id, ok := v.ids[token]; ok {
+		return id
+	}
+
+	id := uint32(len(v.tokens))
+	v.ids[token] = id
+	v.tokens = append(v.tokens, token)
+
+	return id
+}
+
+// ID returns the ID of a token, or false if it has not been interned.
+func (v *Vocabulary) ID(token string) (uint32, bool) {
+	id, ok := v.ids[token]
+	return id, ok
+}
+
+// Token returns the token for an ID, or false if no token has the ID.
+func (v *Vocabulary) Token(id uint32) (string, bool) {
+	if int(id) >= len(v.tokens) {
+		return "", false
+	}
+
+	return v.tokens[id], true
+}
+
+// Len returns the number of tokens in the vocabulary.
+func (v *Vocabulary) Len() int {
+	return len(v.tokens)
+}
@@ -0,0 +1,29 @@
+package stores
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVocabulary(t *testing.T) {
+	v := NewVocabulary()
+	require.Equal(t, 0, v.Len())
+
+	require.Equal(t, uint32(0), v.Intern("to"))
+	require.Equal(t, uint32(1), v.Intern("be"))
+	require.Equal(t, uint32(0), v.Intern("to"))
+	require.Equal(t, 2, v.Len())
+
+	id, ok := v.ID("be")
+	require.True(t, ok)
+	require.Equal(t, uint32(1), id)
+	_, ok = v.ID("or")
+	require.False(t, ok)
+
+	token, ok := v.Token(1)
+	require.True(t, ok)
+	require.Equal(t, "be", token)
+	_, ok = v.Token(2)
+	require.False(t, ok)
+}
@@ -8,6 +8,12 @@ import (
 	"unicode/utf8"
 )
 
+const (
+
+	// defaultWordID is the name of the default word tokenizer used in its ID.
+	defaultWordID = "default_word"
+)
+
 // DefaultWord is the default tokenizer, designed to be used with
 // bodies of text in english and other latin-based languages.
 type DefaultWord struct {
@@ -31,11 +37,16 @@ type DefaultWord struct {
 	// invalidChars is a slice of invalid characters that must be stripped.
 	// These are virtually all parenthesis and quote marks.
 	invalidChars []rune
+
+	// stripLinebreaks indicates that line breaks are skipped rather than
+	// tokenized.
+	stripLinebreaks bool
 }
 
 // NewDefaultWordTokenizer returns a new default word tokenizer.
 func NewDefaultWordTokenizer(stripLinebreaks bool) *DefaultWord {
 	d := &DefaultWord{
+		stripLinebreaks: stripLinebreaks,
 		skippable: []rune{
 			9,     // \t tab
 			32,    // \s space
@@ -181,6 +192,22 @@ func (tk *DefaultWord) Scanner(data []byte, atEOF bool) (advance int, token []by
 
 }
 
+// ID returns the name of the tokenizer and whether it strips line breaks.
+func (tk *DefaultWord) ID() string {
+	if tk.stripLinebreaks {
+		return defaultWordID + ":strip_linebreaks"
+	}
+
+	return defaultWordID + ":keep_linebreaks"
+}
+
+// EndsSentence returns true if the token is a single stopper character, such
+// as a full stop or question mark.
+func (tk *DefaultWord) EndsSentence(token string) bool {
+	r, width := utf8.DecodeRuneInString(token)
+	return width > 0 && width == len(token) && runeInSlice(r, tk.stoppers)
+}
+
 // sanitize will remove any invalid characters from a byte slice.
 func (tk *DefaultWord) sanitize(data []byte) []byte {
 	rs := bytes.Runes(data)
@@ -79,3 +79,21 @@ func TestFormat(t *testing.T) {
 	tokens := []string{"i", "am", "sick", "of", "Mr", ".", "Bingley", ",", "cried", "his", "wife", ".", "he's", "like", "a", "character", "from", "a", "Jane", "Austen", "novel", "!"}
 	require.Equal(t, "I am sick of Mr. Bingley, cried his wife. He's like a character from a Jane Austen novel!", tk.Format(tokens))
 }
+
+func TestEndsSentence(t *testing.T) {
+	tk := NewDefaultWordTokenizer(true)
+	require.True(t, tk.EndsSentence("."))
+	require.True(t, tk.EndsSentence("?"))
+	require.True(t, tk.EndsSentence("。"))
+	require.False(t, tk.EndsSentence(","))
+	require.False(t, tk.EndsSentence("..."))
+	require.False(t, tk.EndsSentence("word"))
+	require.False(t, tk.EndsSentence(""))
+
+	var _ SentenceSplitter = tk
+}
+
+func TestDefaultWordID(t *testing.T) {
+	require.Equal(t, "default_word:strip_linebreaks", NewDefaultWordTokenizer(true).ID())
+	require.Equal(t, "default_word:keep_linebreaks", NewDefaultWordTokenizer(false).ID())
+}
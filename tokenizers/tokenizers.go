@@ -13,6 +13,36 @@ type Tokenizer interface {
 	Format([]string) string
 }
 
+// SentenceSplitter is an optional interface which can be implemented by a
+// Tokenizer that is able to identify the tokens which end a sentence.
+type SentenceSplitter interface {
+
+	// EndsSentence returns true if the token ends a sentence.
+	EndsSentence(token string) bool
+}
+
+// Identifier is an optional interface which can be implemented by a Tokenizer
+// so that an index saved with it can be restored with an equivalent tokenizer.
+type Identifier interface {
+
+	// ID returns the name and settings of the tokenizer. Tokenizers with the
+	// same ID must tokenize text in the same way.
+	ID() string
+}
+
+// FromID returns a new tokenizer from this package matching an ID returned by
+// a tokenizer's ID method. It returns false if the ID is not recognised.
+func FromID(id string) (Tokenizer, bool) {
+	switch id {
+	case defaultWordID + ":strip_linebreaks":
+		return NewDefaultWordTokenizer(true), true
+	case defaultWordID + ":keep_linebreaks":
+		return NewDefaultWordTokenizer(false), true
+	}
+
+	return nil, false
+}
+
 // runeInSlice returns true if the rune was found in the slice of runes.
 func runeInSlice(c rune, r []rune) bool {
 	for i := 0; i < len(r); i++ {
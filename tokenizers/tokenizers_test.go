@@ -18,3 +18,15 @@ func TestRuneInSlice(t *testing.T) {
 	require.Equal(t, true, runeInSlice(65281, r))
 
 }
+
+func TestFromID(t *testing.T) {
+	for _, strip := range []bool{true, false} {
+		d := NewDefaultWordTokenizer(strip)
+		tk, ok := FromID(d.ID())
+		require.True(t, ok)
+		require.Equal(t, d, tk)
+	}
+
+	_, ok := FromID("unknown")
+	require.False(t, ok)
+}
@@ -0,0 +1,44 @@
+package ngrams
+
+import (
+	"context"
+	"io"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+// Unlearn parses a string into ngrams and removes them from the index, undoing
+// exactly the counts that Parse would have added for the same string. Variations
+// and keys are removed from the store once nothing is left indexed for them.
+// Stores which don't implement stores.Remover have each changed key deleted and
+// added again, which is much slower.
+func (i *Index) Unlearn(str string) (tokens []string, err error) {
+	return i.UnlearnContext(context.Background(), str)
+}
+
+// UnlearnContext parses a string into ngrams and removes them from the index,
+// as per Unlearn. Unlearning stops with the context's error if it is
+// cancelled, leaving any ngrams which were not yet removed in the store.
+func (i *Index) UnlearnContext(ctx context.Context, str string) (tokens []string, err error) {
+	return i.parse(ctx, str, removeNgram)
+}
+
+// UnlearnReader reads from an io.Reader and removes the extracted ngrams from
+// the index, undoing exactly the counts that Read would have added for the
+// same data.
+func (i *Index) UnlearnReader(r io.Reader) error {
+	return i.UnlearnReaderContext(context.Background(), r)
+}
+
+// UnlearnReaderContext reads from an io.Reader and removes the extracted ngrams
+// from the index, as per UnlearnReader. Unlearning stops with the context's
+// error if it is cancelled.
+func (i *Index) UnlearnReaderContext(ctx context.Context, r io.Reader) error {
+	return i.read(ctx, r, removeNgram)
+}
+
+// removeNgram removes a single count of an ngram from a store. The context is
+// already checked for cancellation between tokens.
+func removeNgram(ctx context.Context, s stores.Store, key, future string) error {
+	return stores.Remove(s, key, future)
+}
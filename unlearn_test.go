@@ -0,0 +1,78 @@
+package ngrams
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	stores "github.com/mochi-co/ngrams/stores"
+)
+
+func TestUnlearn(t *testing.T) {
+	for _, o := range []Options{
+		{},
+		{AllOrders: true},
+		{SentenceMarkers: true},
+		{Smoother: NewKneserNey(0.75)},
+		{Store: stores.NewCompactStore()},
+		{Store: &plainStore{stores.NewMemoryStore()}},
+	} {
+		i := NewIndex(3, &o)
+		_, err := i.Parse(sentencesText)
+		require.NoError(t, err)
+
+		// plainStore hides every optional interface, so the underlying store
+		// is compared.
+		s := i.Store
+		if p, ok := s.(*plainStore); ok {
+			s = p.Store
+		}
+		before := storeGrams(t, s)
+
+		_, err = i.Parse(smoothingText)
+		require.NoError(t, err)
+		require.NotEqual(t, before, storeGrams(t, s))
+
+		tokens, err := i.Unlearn(smoothingText)
+		require.NoError(t, err)
+		require.NotEmpty(t, tokens)
+		require.Equal(t, before, storeGrams(t, s))
+
+		// Unlearning everything leaves nothing behind.
+		err = i.UnlearnReader(strings.NewReader(sentencesText))
+		require.NoError(t, err)
+		require.Empty(t, storeGrams(t, s))
+	}
+}
+
+func TestUnlearnUnseen(t *testing.T) {
+	i := NewIndex(3, nil)
+	i.Parse("to be or not to be")
+	before := storeGrams(t, i.Store)
+
+	// Ngrams which were never learned are ignored.
+	_, err := i.Unlearn("that is the question")
+	require.NoError(t, err)
+	require.Equal(t, before, storeGrams(t, i.Store))
+
+	_, err = i.Unlearn("to be or")
+	require.NoError(t, err)
+	require.Equal(t, int64(0), i.Count("to", "be", "or"))
+	require.Equal(t, int64(1), i.Count("be", "or", "not"))
+}
+
+func TestUnlearnContext(t *testing.T) {
+	i := NewIndex(3, nil)
+	i.Parse("to be or not to be")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := i.UnlearnContext(ctx, "to be or not to be")
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, int64(1), i.Count("to", "be", "or"))
+
+	err = i.UnlearnReaderContext(ctx, strings.NewReader("to be or not to be"))
+	require.Equal(t, context.Canceled, err)
+}